@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v5"
@@ -36,9 +38,38 @@ func main() {
 		ClientSecret: os.Getenv("DISCORD_CLIENT_SECRET"),
 		RedirectURI:  os.Getenv("DISCORD_REDIRECT_URI"),
 		Scopes:       []string{"identify"},
+		// Only set when running against a fake Discord
+		BaseURL: os.Getenv("DISCORD_API_URL"),
 	}
 
-	handlers := handlers.New(os.Getenv("KEY"), discord, db)
+	// Staff are bot operators, given as a comma-separated list of user IDs, the same
+	// as STAFF in the backend
+	var staff []string
+	for _, id := range strings.Split(os.Getenv("STAFF"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			staff = append(staff, id)
+		}
+	}
+
+	handlers := handlers.New(os.Getenv("KEY"), discord, db, staff)
+
+	// Scheduled configs go live (and revert) on the next tick after their time,
+	// so within half a minute of it
+	go func() {
+		for range time.Tick(30 * time.Second) {
+			handlers.RunScheduledConfigs(context.Background())
+		}
+	}()
+
+	// Expired permissions are cleared every minute, which also logs out anyone
+	// left with no permissions at all
+	go func() {
+		for range time.Tick(time.Minute) {
+			if err := db.ClearExpiredPermissions(context.Background()); err != nil {
+				slog.Error("couldn't clear expired permissions", "sql_error", err.Error())
+			}
+		}
+	}()
 
 	app := echo.New()
 	app.Use(echomiddleware.RequestLoggerWithConfig(echomiddleware.RequestLoggerConfig{
@@ -74,18 +105,58 @@ func main() {
 	})
 	app.GET("/api/archives/:id", handlers.GetArchive)
 
+	// Exports are downloaded through a form submission, so the key can be in the body
+	app.POST("/api/guilds/:guildId/export", handlers.ExportCases, middleware.FormAPIKeyAuth(db))
+
 	g := app.Group("/api")
 	g.Use(middleware.APIKeyAuth(db))
 	g.POST("/auth/logout", handlers.Logout)
 	g.POST("/auth/refresh", handlers.Refresh)
+	g.GET("/auth/sessions", handlers.ListSessions)
+	g.POST("/auth/sessions/revoke-others", handlers.RevokeOtherSessions)
+	g.DELETE("/auth/sessions/:id", handlers.RevokeSession)
 	g.GET("/guilds/available", handlers.Available)
 	g.GET("/guilds/my-permissions", handlers.MyPermissions)
 	g.GET("/guilds/:guildId", handlers.GetGuild)
 	g.POST("/guilds/:guildId/check-permission", handlers.CheckPermission)
 	g.GET("/guilds/:guildId/config", handlers.GetConfig)
 	g.POST("/guilds/:guildId/config", handlers.SaveConfig)
+	g.PATCH("/guilds/:guildId/config", handlers.PatchConfig)
+	g.GET("/guilds/:guildId/config/diff", handlers.DiffConfigRevisions)
+	g.GET("/guilds/:guildId/config/drafts", handlers.ListConfigDrafts)
+	g.POST("/guilds/:guildId/config/drafts", handlers.CreateConfigDraft)
+	g.GET("/guilds/:guildId/config/drafts/:id", handlers.GetConfigDraft)
+	g.POST("/guilds/:guildId/config/drafts/:id", handlers.UpdateConfigDraft)
+	g.POST("/guilds/:guildId/config/drafts/:id/approve", handlers.ApproveConfigDraft)
+	g.POST("/guilds/:guildId/config/drafts/:id/reject", handlers.RejectConfigDraft)
+	g.POST("/guilds/:guildId/config/lint", handlers.LintConfig)
+	g.GET("/guilds/:guildId/config/plugins/:plugin", handlers.GetPluginConfig)
+	g.PUT("/guilds/:guildId/config/plugins/:plugin", handlers.SavePluginConfig)
+	g.GET("/guilds/:guildId/config/preview", handlers.PreviewConfig)
+	g.GET("/guilds/:guildId/config/revisions", handlers.ListConfigRevisions)
+	g.GET("/guilds/:guildId/config/revisions/:id", handlers.GetConfigRevision)
+	g.POST("/guilds/:guildId/config/revisions/:id/restore", handlers.RestoreConfigRevision)
+	g.GET("/guilds/:guildId/config/scheduled", handlers.ListScheduledConfigs)
+	g.POST("/guilds/:guildId/config/scheduled", handlers.ScheduleConfig)
+	g.GET("/guilds/:guildId/config/scheduled/:id", handlers.GetScheduledConfig)
+	g.POST("/guilds/:guildId/config/scheduled/:id/cancel", handlers.CancelScheduledConfig)
 	g.GET("/guilds/:guildId/permissions", handlers.GetPermissions)
 	g.POST("/guilds/:guildId/set-target-permissions", handlers.SetTargetPermissions)
+	g.GET("/guilds/:guildId/cases", handlers.ListCases)
+	g.GET("/guilds/:guildId/cases/:caseNumber", handlers.GetCase)
+	g.GET("/guilds/:guildId/pre-import", handlers.PreImport)
+	g.POST("/guilds/:guildId/import", handlers.ImportCases)
+	g.GET("/staff/status", handlers.StaffStatus)
+	g.POST("/staff/users/:userId/revoke-sessions", handlers.RevokeUserSessions)
+	g.GET("/global/config", handlers.GetGlobalConfig)
+	g.POST("/global/config", handlers.SaveGlobalConfig)
+	g.POST("/global/config/validate", handlers.ValidateGlobalConfig)
+	g.GET("/global/config/revisions", handlers.ListGlobalConfigRevisions)
+	g.GET("/global/config/revisions/:id", handlers.GetGlobalConfigRevision)
+	g.GET("/templates", handlers.ListTemplates)
+	g.GET("/templates/:name", handlers.GetTemplate)
+	g.PUT("/templates/:name", handlers.SaveTemplate)
+	g.DELETE("/templates/:name", handlers.DeleteTemplate)
 
 	if err := app.Start("0.0.0.0:8080"); err != nil {
 		app.Logger.Error("Failed to start server", "error", err)
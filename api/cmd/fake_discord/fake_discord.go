@@ -0,0 +1,208 @@
+// Command fake_discord stands in for Discord's OAuth2 and user endpoints, so the
+// dashboard login can be run locally without real credentials or a network. Point
+// the API at it with DISCORD_API_URL=http://127.0.0.1:8081/api, with any values for
+// DISCORD_CLIENT_ID and DISCORD_CLIENT_SECRET.
+//
+// The authorize page lists the fake users to log in as. They're set with
+// FAKE_DISCORD_USERS, a comma-separated list of id:username pairs, and the user
+// still needs api_permissions in the database to get into the dashboard.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/labstack/echo/v5"
+
+	"github.com/owdiscord/athena/api/internal/services/discord"
+)
+
+const (
+	defaultAddr  = "127.0.0.1:8081"
+	defaultUsers = "100000000000000001:developer"
+	// codeTTL is how long an authorization code can be exchanged for, like Discord's
+	codeTTL = 10 * time.Minute
+)
+
+// grant is an authorization code waiting to be exchanged
+type grant struct {
+	userID      string
+	redirectURI string
+	challenge   string
+	expiresAt   time.Time
+}
+
+type server struct {
+	users []discord.User
+
+	mu     sync.Mutex
+	grants map[string]grant
+	tokens map[string]string
+}
+
+func main() {
+	godotenv.Load("../.env", ".env")
+
+	addr := os.Getenv("FAKE_DISCORD_ADDR")
+	if addr == "" {
+		addr = defaultAddr
+	}
+
+	usersEnv := os.Getenv("FAKE_DISCORD_USERS")
+	if usersEnv == "" {
+		usersEnv = defaultUsers
+	}
+	var users []discord.User
+	for _, entry := range strings.Split(usersEnv, ",") {
+		id, username, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" || username == "" {
+			log.Fatalf("invalid FAKE_DISCORD_USERS entry %q, expected id:username", entry)
+		}
+		users = append(users, discord.User{ID: id, Username: username})
+	}
+
+	s := &server{users: users, grants: map[string]grant{}, tokens: map[string]string{}}
+
+	app := echo.New()
+	g := app.Group("/api")
+	g.GET("/oauth2/authorize", s.authorize)
+	g.POST("/oauth2/authorize", s.approve)
+	g.POST("/oauth2/token", s.token)
+	g.GET("/users/@me", s.me)
+
+	log.Printf("fake discord listening on http://%s/api with %d users", addr, len(users))
+	if err := app.Start(addr); err != nil {
+		app.Logger.Error("Failed to start server", "error", err)
+	}
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!doctype html>
+<title>Fake Discord</title>
+<h1>Log in as</h1>
+<form method="post">
+	{{range $key, $value := .Params}}<input type="hidden" name="{{$key}}" value="{{index $value 0}}">
+	{{end}}
+	{{range .Users}}<p><button name="user_id" value="{{.ID}}">{{.Username}} ({{.ID}})</button></p>
+	{{end}}
+</form>
+`))
+
+// authorize shows the consent screen, which here is a choice of fake users
+func (s *server) authorize(c *echo.Context) error {
+	if c.QueryParam("response_type") != "code" || c.QueryParam("redirect_uri") == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "expected response_type=code and a redirect_uri")
+	}
+	if method := c.QueryParam("code_challenge_method"); method != "" && method != "S256" {
+		return echo.NewHTTPError(http.StatusBadRequest, "unsupported code_challenge_method")
+	}
+
+	c.Response().Header().Set("Content-Type", "text/html; charset=utf-8")
+	return authorizePage.Execute(c.Response(), map[string]any{
+		"Params": c.QueryParams(),
+		"Users":  s.users,
+	})
+}
+
+// approve hands out a code for the chosen user and sends them back to the app
+func (s *server) approve(c *echo.Context) error {
+	userID := c.FormValue("user_id")
+	if s.user(userID) == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "unknown user")
+	}
+
+	redirect, err := url.Parse(c.FormValue("redirect_uri"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid redirect_uri")
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.grants[code] = grant{
+		userID:      userID,
+		redirectURI: c.FormValue("redirect_uri"),
+		challenge:   c.FormValue("code_challenge"),
+		expiresAt:   time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	query := redirect.Query()
+	query.Set("code", code)
+	if state := c.FormValue("state"); state != "" {
+		query.Set("state", state)
+	}
+	redirect.RawQuery = query.Encode()
+
+	return c.Redirect(http.StatusFound, redirect.String())
+}
+
+// token exchanges a code for an access token, checking it the way Discord does
+func (s *server) token(c *echo.Context) error {
+	if c.FormValue("grant_type") != "authorization_code" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := c.FormValue("code")
+	g, ok := s.grants[code]
+	delete(s.grants, code)
+	if !ok || time.Now().After(g.expiresAt) || g.redirectURI != c.FormValue("redirect_uri") {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+	}
+	if g.challenge != "" && discord.PKCEChallenge(c.FormValue("code_verifier")) != g.challenge {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier doesn't match"})
+	}
+
+	accessToken := randomString()
+	s.tokens[accessToken] = g.userID
+
+	return c.JSON(http.StatusOK, discord.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    604800,
+		RefreshToken: randomString(),
+		Scope:        "identify",
+	})
+}
+
+func (s *server) me(c *echo.Context) error {
+	accessToken, ok := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]any{"message": "401: Unauthorized", "code": 0})
+	}
+
+	s.mu.Lock()
+	userID := s.tokens[accessToken]
+	s.mu.Unlock()
+
+	user := s.user(userID)
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]any{"message": "401: Unauthorized", "code": 0})
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+func (s *server) user(id string) *discord.User {
+	for i := range s.users {
+		if s.users[i].ID == id {
+			return &s.users[i]
+		}
+	}
+	return nil
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
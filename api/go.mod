@@ -8,9 +8,9 @@ require golang.org/x/time v0.14.0 // indirect
 
 require (
 	filippo.io/edwards25519 v1.2.0 // indirect
-	github.com/go-sql-driver/mysql v1.9.3 // indirect
+	github.com/go-sql-driver/mysql v1.9.3
 	github.com/google/uuid v1.6.0
-	github.com/jmoiron/sqlx v1.4.0 // indirect
-	github.com/labstack/echo/v5 v5.0.4 // indirect
+	github.com/jmoiron/sqlx v1.4.0
+	github.com/labstack/echo/v5 v5.0.4
 	gopkg.in/yaml.v3 v3.0.1
 )
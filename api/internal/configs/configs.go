@@ -0,0 +1,320 @@
+// Package configs understands the YAML configs we keep in the configs table:
+// parsing them, validating them and pointing at where in the YAML a problem is.
+// Validation here follows validateGuildConfig in backend/src/configValidator.ts
+// as far as the structure goes; plugin-specific options are left to the bot.
+package configs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a single problem with a config, along with where it is in the YAML.
+// Line and Column are 1-based, and zero when the position isn't known.
+type Error struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+func (e Error) Error() string {
+	msg := e.Message
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		msg += fmt.Sprintf(" (line %d, column %d)", e.Line, e.Column)
+	case e.Line > 0:
+		msg += fmt.Sprintf(" (line %d)", e.Line)
+	}
+	return msg
+}
+
+// Strings flattens errors into the plain messages the dashboard displays
+func Strings(errs []Error) []string {
+	out := make([]string, 0, len(errs))
+	for _, err := range errs {
+		out = append(out, err.Error())
+	}
+	return out
+}
+
+var yamlLineRegex = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// Parse reads a config into a YAML node tree, returning the top-level node of
+// the document. An empty document gives a nil node and no error. Configs with
+// object anchors or aliases, duplicate keys, or that are too large, are refused.
+func Parse(source string) (*yaml.Node, error) {
+	doc, err := ParseDocument(source)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// ParseDocument is Parse, but returns the document node itself so comments at the
+// very top and bottom of the file survive being encoded again
+func ParseDocument(source string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(source), &doc); err != nil {
+		parseErr := Error{Message: err.Error()}
+		if m := yamlLineRegex.FindStringSubmatch(err.Error()); m != nil {
+			parseErr.Line, _ = strconv.Atoi(m[1])
+			parseErr.Message = strings.TrimPrefix(err.Error(), m[0])
+		}
+		return nil, parseErr
+	}
+
+	if err := checkSafety(&doc); err != nil {
+		return nil, err
+	}
+	if err := checkDuplicateKeys(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+var snowflakeRegex = regexp.MustCompile(`^[1-9][0-9]{5,19}$`)
+
+// ValidateGuildConfig validates a parsed guild config, returning every problem
+// found. A nil result means the config is good to save.
+func ValidateGuildConfig(root *yaml.Node) []Error {
+	v := &validator{known: GuildPlugins}
+	v.guildConfig(root)
+	return v.errs
+}
+
+// ValidateGlobalConfig validates the parsed global config, which the bot loads
+// for its global plugins. Besides plugins and levels, it has the dashboard's url
+// and the bot owners, read by getBaseUrl and isOwner in backend/src/pluginUtils.ts.
+// The bot doesn't validate the global config at all, so this is only as strict
+// as what it reads.
+func ValidateGlobalConfig(root *yaml.Node) []Error {
+	v := &validator{known: GlobalPlugins}
+	v.globalConfig(root)
+	return v.errs
+}
+
+type validator struct {
+	errs []Error
+	// known are the plugin names the config may configure
+	known map[string]bool
+}
+
+func (v *validator) fail(path string, node *yaml.Node, format string, args ...any) {
+	v.errs = append(v.errs, Error{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+		Line:    node.Line,
+		Column:  node.Column,
+	})
+}
+
+func (v *validator) guildConfig(root *yaml.Node) {
+	if root == nil || isNull(root) {
+		return
+	}
+	if root.Kind != yaml.MappingNode {
+		v.fail("", root, "config must be a mapping")
+		return
+	}
+
+	forEachPair(root, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "prefix":
+			if !isString(value) {
+				v.fail("prefix", value, "expected a string")
+			}
+		case "levels":
+			v.levels(value)
+		case "plugins":
+			v.plugins(value)
+		case "extends":
+			if !isString(value) || !IsTemplateName(value.Value) {
+				v.fail("extends", value, "expected the name of a config template")
+			}
+		default:
+			v.fail(key.Value, key, "unknown option")
+		}
+	})
+}
+
+func (v *validator) globalConfig(root *yaml.Node) {
+	if root == nil || isNull(root) {
+		return
+	}
+	if root.Kind != yaml.MappingNode {
+		v.fail("", root, "config must be a mapping")
+		return
+	}
+
+	forEachPair(root, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "url":
+			if !isString(value) {
+				v.fail("url", value, "expected a string")
+			}
+		case "owners":
+			v.owners(value)
+		case "levels":
+			v.levels(value)
+		case "plugins":
+			v.plugins(value)
+		default:
+			v.fail(key.Value, key, "unknown option")
+		}
+	})
+}
+
+func (v *validator) owners(node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.SequenceNode {
+		v.fail("owners", node, "expected a list of user IDs")
+		return
+	}
+
+	for i, owner := range node.Content {
+		path := fmt.Sprintf("owners[%d]", i)
+		switch {
+		case !snowflakeRegex.MatchString(owner.Value):
+			v.fail(path, owner, "invalid snowflake ID")
+		case !isString(owner):
+			// The bot compares these against user IDs, which are strings, and an
+			// ID this long doesn't survive being read as a number anyway
+			v.fail(path, owner, "user IDs must be quoted")
+		}
+	}
+}
+
+func (v *validator) levels(node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		v.fail("levels", node, "expected a mapping of IDs to levels")
+		return
+	}
+
+	forEachPair(node, func(key, value *yaml.Node) {
+		path := "levels." + key.Value
+		if !snowflakeRegex.MatchString(key.Value) {
+			v.fail(path, key, "invalid snowflake ID")
+		}
+		if !isNumber(value) {
+			v.fail(path, value, "expected a number")
+		}
+	})
+}
+
+func (v *validator) plugins(node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		v.fail("plugins", node, "expected a mapping of plugin names to options")
+		return
+	}
+
+	forEachPair(node, func(key, value *yaml.Node) {
+		path := "plugins." + key.Value
+		if !v.known[key.Value] {
+			if suggestion := closestPlugin(key.Value, v.known); suggestion != "" {
+				v.fail(path, key, "unknown plugin, did you mean %s?", suggestion)
+			} else {
+				v.fail(path, key, "unknown plugin")
+			}
+			return
+		}
+		v.pluginOptions(path, value)
+	})
+}
+
+func (v *validator) pluginOptions(path string, node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.fail(path, node, "invalid options specified for plugin")
+		return
+	}
+
+	forEachPair(node, func(key, value *yaml.Node) {
+		optPath := path + "." + key.Value
+		switch key.Value {
+		case "config":
+			if !isNull(value) && value.Kind != yaml.MappingNode {
+				v.fail(optPath, value, "expected a mapping")
+			}
+		case "overrides":
+			v.overrides(optPath, value)
+		case "replaceDefaultOverrides", "enabled":
+			if !isBool(value) {
+				v.fail(optPath, value, "expected true or false")
+			}
+		default:
+			v.fail(optPath, key, "unknown plugin option")
+		}
+	})
+}
+
+func (v *validator) overrides(path string, node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.SequenceNode {
+		v.fail(path, node, "expected a list of overrides")
+		return
+	}
+
+	for i, override := range node.Content {
+		overridePath := fmt.Sprintf("%s[%d]", path, i)
+		if override.Kind != yaml.MappingNode {
+			v.fail(overridePath, override, "expected a mapping")
+			continue
+		}
+
+		forEachPair(override, func(key, value *yaml.Node) {
+			switch {
+			case key.Value == "config":
+				if !isNull(value) && value.Kind != yaml.MappingNode {
+					v.fail(overridePath+".config", value, "expected a mapping")
+				}
+			case !overrideCriteria[key.Value]:
+				v.fail(overridePath+"."+key.Value, key, "unknown override criteria")
+			}
+		})
+	}
+}
+
+// --- helpers ---
+
+// forEachPair calls fn for each key/value pair of a mapping node
+func forEachPair(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i], node.Content[i+1])
+	}
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
+
+func isString(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str"
+}
+
+func isNumber(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && (node.ShortTag() == "!!int" || node.ShortTag() == "!!float")
+}
+
+func isBool(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!bool"
+}
@@ -0,0 +1,250 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validate parses source and runs it through fn, failing the test if it doesn't parse
+func validate(t *testing.T, source string, fn func(*yaml.Node) []Error) []Error {
+	t.Helper()
+	root, err := Parse(source)
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	return fn(root)
+}
+
+func TestValidateGlobalConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []Error
+	}{
+		{
+			name:   "empty",
+			source: "",
+		},
+		{
+			name: "everything the bot reads",
+			source: "url: https://athena.example\n" +
+				"owners:\n  - \"106391128718245888\"\n  - \"108552944961454080\"\n" +
+				"levels:\n  \"106391128718245888\": 100\n" +
+				"plugins:\n  bot_control:\n    config:\n      prefix: \"!!\"\n    overrides:\n      - level: \">=100\"\n        config:\n          can_use: true\n" +
+				"  guild_config_reloader: {}\n",
+		},
+		{
+			name:   "guild plugin",
+			source: "plugins:\n  automod: {}\n",
+			want:   []Error{{Path: "plugins.automod", Message: "unknown plugin", Line: 2, Column: 3}},
+		},
+		{
+			name:   "guild only options",
+			source: "prefix: \"!\"\nextends: base\n",
+			want: []Error{
+				{Path: "prefix", Message: "unknown option", Line: 1, Column: 1},
+				{Path: "extends", Message: "unknown option", Line: 2, Column: 1},
+			},
+		},
+		{
+			name:   "url not a string",
+			source: "url: [a]\n",
+			want:   []Error{{Path: "url", Message: "expected a string", Line: 1, Column: 6}},
+		},
+		{
+			name:   "owners not a list",
+			source: "owners: \"106391128718245888\"\n",
+			want:   []Error{{Path: "owners", Message: "expected a list of user IDs", Line: 1, Column: 9}},
+		},
+		{
+			name:   "bad owners",
+			source: "owners:\n  - someone\n  - 106391128718245888\n  - {id: 1}\n",
+			want: []Error{
+				{Path: "owners[0]", Message: "invalid snowflake ID", Line: 2, Column: 5},
+				{Path: "owners[1]", Message: "user IDs must be quoted", Line: 3, Column: 5},
+				{Path: "owners[2]", Message: "invalid snowflake ID", Line: 4, Column: 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validate(t, tt.source, ValidateGlobalConfig)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateGlobalConfig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   Error
+	}{
+		{
+			name:   "unclosed list",
+			source: "prefix: \"!\"\nplugins:\n  automod: [\n",
+			want:   Error{Message: "did not find expected node content", Line: 3},
+		},
+		{
+			name:   "bad indentation",
+			source: "prefix: \"!\"\nlevels: none\n  plugins: {}\n",
+			want:   Error{Message: "mapping values are not allowed in this context", Line: 3},
+		},
+		{
+			name:   "tab",
+			source: "prefix: \"!\"\n\tplugins: {}\n",
+			want:   Error{Message: "found character that cannot start any token", Line: 2},
+		},
+		{
+			name:   "duplicate key",
+			source: "prefix: \"!\"\nplugins:\n  automod: {}\n  automod: {}\n",
+			want:   Error{Message: `duplicated mapping key "automod"`, Line: 4, Column: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			got, ok := err.(Error)
+			if !ok {
+				t.Fatalf("Parse() = %v, want an Error", err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGuildConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []Error
+	}{
+		{
+			name:   "empty",
+			source: "",
+		},
+		{
+			name: "valid",
+			source: "prefix: \"!\"\n" +
+				"levels:\n  \"106391128718245888\": 100\n  \"108552944961454080\": 50.5\n" +
+				"plugins:\n" +
+				"  automod:\n    enabled: true\n    replaceDefaultOverrides: false\n    config:\n      rules: {}\n" +
+				"    overrides:\n      - level: \">=50\"\n        config:\n          can_view: true\n" +
+				"      - any:\n          - channel: \"108552944961454080\"\n        config: {}\n" +
+				"  utility: {}\n",
+		},
+		{
+			name:   "not a mapping",
+			source: "- prefix\n",
+			want:   []Error{{Message: "config must be a mapping", Line: 1, Column: 1}},
+		},
+		{
+			name:   "unknown option",
+			source: "prefix: \"!\"\nprefixes: [\"!\"]\n",
+			want:   []Error{{Path: "prefixes", Message: "unknown option", Line: 2, Column: 1}},
+		},
+		{
+			name:   "prefix not a string",
+			source: "prefix: 1\n",
+			want:   []Error{{Path: "prefix", Message: "expected a string", Line: 1, Column: 9}},
+		},
+		{
+			name:   "bad levels",
+			source: "levels:\n  everyone: 0\n  \"106391128718245888\": high\n",
+			want: []Error{
+				{Path: "levels.everyone", Message: "invalid snowflake ID", Line: 2, Column: 3},
+				{Path: "levels.106391128718245888", Message: "expected a number", Line: 3, Column: 25},
+			},
+		},
+		{
+			name:   "levels not a mapping",
+			source: "levels: [1]\n",
+			want:   []Error{{Path: "levels", Message: "expected a mapping of IDs to levels", Line: 1, Column: 9}},
+		},
+		{
+			name:   "plugin typo",
+			source: "plugins:\n  automd: {}\n",
+			want:   []Error{{Path: "plugins.automd", Message: "unknown plugin, did you mean automod?", Line: 2, Column: 3}},
+		},
+		{
+			name:   "unknown plugin",
+			source: "plugins:\n  something_else: {}\n",
+			want:   []Error{{Path: "plugins.something_else", Message: "unknown plugin", Line: 2, Column: 3}},
+		},
+		{
+			name:   "global plugin",
+			source: "plugins:\n  bot_control: {}\n",
+			want:   []Error{{Path: "plugins.bot_control", Message: "unknown plugin", Line: 2, Column: 3}},
+		},
+		{
+			name:   "bad plugin options",
+			source: "plugins:\n  automod:\n    enabled: yes please\n    config: [1]\n    settings: {}\n",
+			want: []Error{
+				{Path: "plugins.automod.enabled", Message: "expected true or false", Line: 3, Column: 14},
+				{Path: "plugins.automod.config", Message: "expected a mapping", Line: 4, Column: 13},
+				{Path: "plugins.automod.settings", Message: "unknown plugin option", Line: 5, Column: 5},
+			},
+		},
+		{
+			name:   "plugin options not a mapping",
+			source: "plugins:\n  automod: true\n",
+			want:   []Error{{Path: "plugins.automod", Message: "invalid options specified for plugin", Line: 2, Column: 12}},
+		},
+		{
+			name:   "bad overrides",
+			source: "plugins:\n  automod:\n    overrides:\n      - levle: \">=50\"\n        config: 1\n      - true\n",
+			want: []Error{
+				{Path: "plugins.automod.overrides[0].levle", Message: "unknown override criteria", Line: 4, Column: 9},
+				{Path: "plugins.automod.overrides[0].config", Message: "expected a mapping", Line: 5, Column: 17},
+				{Path: "plugins.automod.overrides[1]", Message: "expected a mapping", Line: 6, Column: 9},
+			},
+		},
+		{
+			name:   "extends a template",
+			source: "extends: community-base\nprefix: \"!\"\n",
+		},
+		{
+			name:   "extends something that can't be a template",
+			source: "extends: Community Base\n",
+			want:   []Error{{Path: "extends", Message: "expected the name of a config template", Line: 1, Column: 10}},
+		},
+		{
+			name:   "overrides not a list",
+			source: "plugins:\n  automod:\n    overrides: {level: 50}\n",
+			want:   []Error{{Path: "plugins.automod.overrides", Message: "expected a list of overrides", Line: 3, Column: 16}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validate(t, tt.source, ValidateGuildConfig)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateGuildConfig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	tests := []struct {
+		err  Error
+		want string
+	}{
+		{Error{Message: "broken"}, "broken"},
+		{Error{Path: "prefix", Message: "expected a string", Line: 1, Column: 9}, "prefix: expected a string (line 1, column 9)"},
+		{Error{Message: "did not find expected key", Line: 4}, "did not find expected key (line 4)"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DiffContext is how many unchanged lines surround each hunk of a unified diff
+const DiffContext = 3
+
+type lineOp byte
+
+const (
+	opEqual  lineOp = ' '
+	opDelete lineOp = '-'
+	opInsert lineOp = '+'
+)
+
+type lineEdit struct {
+	op   lineOp
+	text string
+}
+
+// UnifiedDiff returns a line-based unified diff turning a into b, labelled with
+// the given file names. Identical inputs give an empty string.
+func UnifiedDiff(fromName, toName, a, b string) string {
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	hunks := 0
+
+	// Line numbers (0-based) in a and b at the start of each edit
+	aLine, bLine := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if e.op != opInsert {
+			aLine[i+1]++
+		}
+		if e.op != opDelete {
+			bLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == opEqual {
+			i++
+			continue
+		}
+
+		// Grow the hunk until there's a long enough run of unchanged lines to end it
+		start := max(0, i-DiffContext)
+		end := i
+		for end < len(edits) {
+			if edits[end].op != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].op == opEqual {
+				run++
+			}
+			if run == len(edits) || run-end > 2*DiffContext {
+				end = min(run, end+DiffContext)
+				break
+			}
+			end = run
+		}
+
+		if hunks == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		hunks++
+
+		aCount, bCount := aLine[end]-aLine[start], bLine[end]-bLine[start]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aLine[start], aCount), hunkRange(bLine[start], bCount))
+		for _, e := range edits[start:end] {
+			out.WriteByte(byte(e.op))
+			out.WriteString(e.text)
+			out.WriteByte('\n')
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+// hunkRange formats a hunk's line range the way diff -u does
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// maxDiffEdits is the most line edits diffLines will look for. Past that, the
+// changed part of the file is shown as removed and added wholesale: a diff that
+// big isn't much use to read, and finding the shortest one gets slow.
+const maxDiffEdits = 1000
+
+// diffLines finds the shortest edit script between a and b using the linear space
+// variant of Myers' algorithm
+func diffLines(a, b []string) []lineEdit {
+	return myers(make([]lineEdit, 0, len(a)+len(b)), a, b, maxDiffEdits)
+}
+
+// commonEnds returns how many lines a and b have in common at the start and end
+func commonEnds(a, b []string) (prefix, suffix int) {
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
+
+// myers appends the shortest edit script turning a into b to edits. The common
+// prefix and suffix are trimmed first, since config edits are usually small changes
+// in a large file. What's left is split at the middle snake of an optimal path and
+// each side diffed in turn, so memory only grows with the length of the inputs.
+// If the script would take more than maxEdits edits, the lines between the prefix
+// and suffix are replaced wholesale instead. A negative maxEdits means no limit.
+func myers(edits []lineEdit, a, b []string, maxEdits int) []lineEdit {
+	prefix, suffix := commonEnds(a, b)
+	for _, line := range a[:prefix] {
+		edits = append(edits, lineEdit{opEqual, line})
+	}
+	a2, b2 := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if len(a2) == 0 || len(b2) == 0 {
+		edits = replaceLines(edits, a2, b2)
+	} else if x, y, u, v, ok := middleSnake(a2, b2, maxEdits); !ok {
+		edits = replaceLines(edits, a2, b2)
+	} else {
+		// Either side of the snake takes fewer edits than the whole, so the
+		// recursion always gets somewhere, and never needs a limit of its own
+		edits = myers(edits, a2[:x], b2[:y], -1)
+		for _, line := range a2[x:u] {
+			edits = append(edits, lineEdit{opEqual, line})
+		}
+		edits = myers(edits, a2[u:], b2[v:], -1)
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, lineEdit{opEqual, line})
+	}
+	return edits
+}
+
+// replaceLines appends edits deleting all of a and inserting all of b
+func replaceLines(edits []lineEdit, a, b []string) []lineEdit {
+	for _, line := range a {
+		edits = append(edits, lineEdit{opDelete, line})
+	}
+	for _, line := range b {
+		edits = append(edits, lineEdit{opInsert, line})
+	}
+	return edits
+}
+
+// middleSnake searches for the shortest edit script from both ends at once, and
+// returns the snake (x, y) to (u, v) where the two searches meet. Everything before
+// (x, y) and after (u, v) is left to be diffed again. It gives up, returning false,
+// if the script would be longer than maxEdits, unless that's negative.
+func middleSnake(a, b []string, maxEdits int) (x, y, u, v int, ok bool) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+
+	// forward[k] is the furthest x reached on diagonal k = x - y from the start, and
+	// backward[c] the same from the end, on the diagonals of the reversed inputs
+	steps := (n + m + 1) / 2
+	offset := steps + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for d := 0; d <= steps; d++ {
+		if maxEdits >= 0 && 2*d-1 > maxEdits {
+			return 0, 0, 0, 0, false
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var startX int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				startX = forward[offset+k+1]
+			} else {
+				startX = forward[offset+k-1] + 1
+			}
+			startY := startX - k
+			endX, endY := startX, startY
+			for endX < n && endY < m && a[endX] == b[endY] {
+				endX++
+				endY++
+			}
+			forward[offset+k] = endX
+
+			if c := delta - k; odd && c >= -(d-1) && c <= d-1 && endX+backward[offset+c] >= n {
+				return startX, startY, endX, endY, true
+			}
+		}
+
+		for c := -d; c <= d; c += 2 {
+			var startX int
+			if c == -d || (c != d && backward[offset+c-1] < backward[offset+c+1]) {
+				startX = backward[offset+c+1]
+			} else {
+				startX = backward[offset+c-1] + 1
+			}
+			startY := startX - c
+			endX, endY := startX, startY
+			for endX < n && endY < m && a[n-1-endX] == b[m-1-endY] {
+				endX++
+				endY++
+			}
+			backward[offset+c] = endX
+
+			if k := delta - c; !odd && k >= -d && k <= d && endX+forward[offset+k] >= n {
+				if maxEdits >= 0 && 2*d > maxEdits {
+					return 0, 0, 0, 0, false
+				}
+				return n - endX, m - endY, n - startX, m - startY, true
+			}
+		}
+	}
+
+	// Unreachable: the searches always meet by the time they've covered both inputs
+	return 0, 0, n, m, true
+}
+
+// Change is a single difference between two parsed configs
+type Change struct {
+	Path string `json:"path"`
+	// Type is one of "added", "removed" or "changed"
+	Type string `json:"type"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// StructuralDiff compares two parsed configs key by key, listing every path that
+// was added, removed or changed. Sequences are compared by index. Either node may
+// be nil for an empty config.
+func StructuralDiff(a, b *yaml.Node) []Change {
+	changes := []Change{}
+	diffNodes("", a, b, &changes)
+	return changes
+}
+
+func diffNodes(path string, a, b *yaml.Node, changes *[]Change) {
+	a, b = nonNull(a), nonNull(b)
+
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*changes = append(*changes, Change{Path: path, Type: "added", To: decode(b)})
+		return
+	case b == nil:
+		*changes = append(*changes, Change{Path: path, Type: "removed", From: decode(a)})
+		return
+	}
+
+	if a.Kind != b.Kind {
+		*changes = append(*changes, Change{Path: path, Type: "changed", From: decode(a), To: decode(b)})
+		return
+	}
+
+	switch a.Kind {
+	case yaml.MappingNode:
+		aKeys, bKeys := mappingValues(a), mappingValues(b)
+		for _, pair := range aKeys {
+			diffNodes(joinPath(path, pair.key), pair.value, lookup(bKeys, pair.key), changes)
+		}
+		for _, pair := range bKeys {
+			if lookup(aKeys, pair.key) == nil {
+				diffNodes(joinPath(path, pair.key), nil, pair.value, changes)
+			}
+		}
+	case yaml.SequenceNode:
+		for i := 0; i < max(len(a.Content), len(b.Content)); i++ {
+			var aItem, bItem *yaml.Node
+			if i < len(a.Content) {
+				aItem = a.Content[i]
+			}
+			if i < len(b.Content) {
+				bItem = b.Content[i]
+			}
+			diffNodes(fmt.Sprintf("%s[%d]", path, i), aItem, bItem, changes)
+		}
+	default:
+		if a.Value != b.Value || a.ShortTag() != b.ShortTag() {
+			*changes = append(*changes, Change{Path: path, Type: "changed", From: decode(a), To: decode(b)})
+		}
+	}
+}
+
+type keyValue struct {
+	key   string
+	value *yaml.Node
+}
+
+// mappingValues returns a mapping's pairs in document order
+func mappingValues(node *yaml.Node) []keyValue {
+	pairs := make([]keyValue, 0, len(node.Content)/2)
+	forEachPair(node, func(key, value *yaml.Node) {
+		pairs = append(pairs, keyValue{key.Value, value})
+	})
+	return pairs
+}
+
+func lookup(pairs []keyValue, key string) *yaml.Node {
+	for _, pair := range pairs {
+		if pair.key == key {
+			return pair.value
+		}
+	}
+	return nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// nonNull treats explicit nulls the same as a missing value
+func nonNull(node *yaml.Node) *yaml.Node {
+	if node == nil || isNull(node) {
+		return nil
+	}
+	return node
+}
+
+// decode turns a node into plain values that encode cleanly as JSON. Mapping keys
+// are always kept as strings, like they would be in the bot.
+func decode(node *yaml.Node) any {
+	switch node.Kind {
+	case yaml.MappingNode:
+		out := make(map[string]any, len(node.Content)/2)
+		forEachPair(node, func(key, value *yaml.Node) {
+			out[key.Value] = decode(value)
+		})
+		return out
+	case yaml.SequenceNode:
+		out := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			out = append(out, decode(item))
+		}
+		return out
+	case yaml.AliasNode:
+		return decode(node.Alias)
+	}
+
+	var out any
+	if err := node.Decode(&out); err != nil {
+		return node.Value
+	}
+	return out
+}
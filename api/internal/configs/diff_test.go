@@ -0,0 +1,317 @@
+package configs
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    "prefix: \"!\"\nlevels:\n  \"123456789\": 100\n",
+			b:    "prefix: \"!\"\nlevels:\n  \"123456789\": 100\n",
+			want: "",
+		},
+		{
+			name: "both empty",
+			a:    "",
+			b:    "",
+			want: "",
+		},
+		{
+			name: "pure insert",
+			a:    "a\nb\nc\n",
+			b:    "a\nb\nnew\nc\n",
+			want: "--- from\n+++ to\n@@ -1,3 +1,4 @@\n a\n b\n+new\n c\n",
+		},
+		{
+			name: "pure delete",
+			a:    "a\nb\nold\nc\n",
+			b:    "a\nb\nc\n",
+			want: "--- from\n+++ to\n@@ -1,4 +1,3 @@\n a\n b\n-old\n c\n",
+		},
+		{
+			name: "from nothing",
+			a:    "",
+			b:    "a\nb\n",
+			want: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "to nothing",
+			a:    "a\n",
+			b:    "",
+			want: "--- from\n+++ to\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "change",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			want: "--- from\n+++ to\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			// Six unchanged lines between the changes is few enough that their
+			// context overlaps, so they share a hunk
+			name: "nearby changes merge",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "1\n2\nthree\n4\n5\n6\n7\n8\n9\nten\n11\n12\n",
+			want: "--- from\n+++ to\n@@ -1,12 +1,12 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n 7\n 8\n 9\n-10\n+ten\n 11\n 12\n",
+		},
+		{
+			// Seven isn't, so they get a hunk each
+			name: "distant changes split",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n",
+			b:    "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\neleven\n12\n13\n",
+			want: "--- from\n+++ to\n@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n@@ -8,6 +8,6 @@\n 8\n 9\n 10\n-11\n+eleven\n 12\n 13\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("from", "to", tt.a, tt.b); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// checkEdits makes sure edits really do turn a into b
+func checkEdits(t *testing.T, a, b []string, edits []lineEdit) {
+	t.Helper()
+	var gotA, gotB []string
+	for _, e := range edits {
+		if e.op != opInsert {
+			gotA = append(gotA, e.text)
+		}
+		if e.op != opDelete {
+			gotB = append(gotB, e.text)
+		}
+	}
+	if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+		t.Fatalf("edits %v don't turn %q into %q", edits, a, b)
+	}
+}
+
+func countChanges(edits []lineEdit) int {
+	changes := 0
+	for _, e := range edits {
+		if e.op != opEqual {
+			changes++
+		}
+	}
+	return changes
+}
+
+// lcs is the length of the longest common subsequence of a and b, done the slow
+// and obvious way
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+func TestDiffLinesIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := func() []string {
+		out := make([]string, rng.Intn(30))
+		for i := range out {
+			out[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return out
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := words(), words()
+		edits := diffLines(a, b)
+		checkEdits(t, a, b, edits)
+		if got, want := countChanges(edits), len(a)+len(b)-2*lcs(a, b); got != want {
+			t.Fatalf("diffLines(%q, %q) took %d edits, want %d", a, b, got, want)
+		}
+	}
+}
+
+func TestDiffLinesLargeRewrite(t *testing.T) {
+	a, b := make([]string, 4000), make([]string, 4000)
+	for i := range a {
+		a[i] = fmt.Sprintf("old_%d: %d", i, i)
+		b[i] = fmt.Sprintf("new_%d: %d", i, i)
+	}
+	// Keep the ends the same, which should still be kept out of the replacement
+	a[0], b[0] = "plugins:", "plugins:"
+	a[len(a)-1], b[len(b)-1] = "end: true", "end: true"
+
+	var edits []lineEdit
+	allocs := testing.AllocsPerRun(1, func() {
+		edits = diffLines(a, b)
+	})
+	checkEdits(t, a, b, edits)
+
+	if edits[0].op != opEqual || edits[len(edits)-1].op != opEqual {
+		t.Errorf("the common first and last lines should be unchanged")
+	}
+	for i, e := range edits[1 : len(edits)-1] {
+		want := opDelete
+		if i >= len(a)-2 {
+			want = opInsert
+		}
+		if e.op != want {
+			t.Fatalf("edit %d is %q, want the changed lines removed then added wholesale", i+1, e.op)
+		}
+	}
+	if allocs > 10 {
+		t.Errorf("diffLines made %v allocations", allocs)
+	}
+}
+
+func TestDiffLinesCap(t *testing.T) {
+	// Every other line changed: the shortest script is twice as long as the
+	// number of changed lines, so this lands either side of maxDiffEdits
+	lines := func(n int, changed string) ([]string, []string) {
+		a, b := make([]string, n), make([]string, n)
+		for i := range a {
+			a[i] = fmt.Sprintf("line %d", i)
+			b[i] = a[i]
+			if i%2 == 1 {
+				b[i] = changed + a[i]
+			}
+		}
+		return a, b
+	}
+
+	tests := []struct {
+		name      string
+		lines     int
+		wholesale bool
+	}{
+		{"under the cap", maxDiffEdits, false},
+		{"over the cap", maxDiffEdits + 4, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := lines(tt.lines, "changed ")
+			edits := diffLines(a, b)
+			checkEdits(t, a, b, edits)
+
+			shortest := len(a) + len(b) - 2*lcs(a, b)
+			if got := countChanges(edits); (got != shortest) != tt.wholesale {
+				t.Errorf("took %d edits where the shortest is %d, want wholesale = %v", got, shortest, tt.wholesale)
+			}
+		})
+	}
+}
+
+func TestStructuralDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Change
+	}{
+		{
+			name: "identical",
+			a:    "prefix: \"!\"\n",
+			b:    "prefix: \"!\"\n",
+			want: []Change{},
+		},
+		{
+			name: "nested change",
+			a:    "plugins:\n  automod:\n    config:\n      rules:\n        spam:\n          enabled: true\n",
+			b:    "plugins:\n  automod:\n    config:\n      rules:\n        spam:\n          enabled: false\n",
+			want: []Change{{Path: "plugins.automod.config.rules.spam.enabled", Type: "changed", From: true, To: false}},
+		},
+		{
+			name: "added and removed",
+			a:    "prefix: \"!\"\nlevels:\n  \"123456789\": 50\n",
+			b:    "levels:\n  \"123456789\": 50\n  \"987654321\": 100\n",
+			want: []Change{
+				{Path: "prefix", Type: "removed", From: "!"},
+				{Path: "levels.987654321", Type: "added", To: 100},
+			},
+		},
+		{
+			name: "sequences by index",
+			a:    "list: [a, b]\n",
+			b:    "list: [a, c, d]\n",
+			want: []Change{
+				{Path: "list[1]", Type: "changed", From: "b", To: "c"},
+				{Path: "list[2]", Type: "added", To: "d"},
+			},
+		},
+		{
+			name: "type changed",
+			a:    "value: 1\n",
+			b:    "value: \"1\"\n",
+			want: []Change{{Path: "value", Type: "changed", From: 1, To: "1"}},
+		},
+		{
+			name: "kind changed",
+			a:    "value: [1]\n",
+			b:    "value: {a: 1}\n",
+			want: []Change{{Path: "value", Type: "changed", From: []any{1}, To: map[string]any{"a": 1}}},
+		},
+		{
+			name: "null is missing",
+			a:    "prefix: \"!\"\nvalue: null\n",
+			b:    "prefix: \"!\"\n",
+			want: []Change{},
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "prefix: \"!\"\n",
+			want: []Change{{Path: "", Type: "added", To: map[string]any{"prefix": "!"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Parse(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := StructuralDiff(a, b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StructuralDiff() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"\n", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\n\nb\n", []string{"a", "", "b"}},
+	}
+	for _, tt := range tests {
+		if got := splitLines(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
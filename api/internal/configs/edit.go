@@ -0,0 +1,302 @@
+package configs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PluginOptions returns the value of plugins.<name> in a parsed document, or nil
+// if that plugin isn't in the config
+func PluginOptions(doc *yaml.Node, name string) *yaml.Node {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil
+	}
+	_, plugins := mappingEntry(root, "plugins")
+	if plugins == nil || plugins.Kind != yaml.MappingNode {
+		return nil
+	}
+	_, options := mappingEntry(plugins, name)
+	return options
+}
+
+// ReplacePlugin swaps the value of plugins.<name> in source for options, adding the
+// plugin (and the plugins mapping) if it isn't there yet, and returns the new config.
+//
+// The edit is made on the node tree, but encoding the whole tree again would lose the
+// blank lines and indentation choices in the rest of the file. So where the plugin
+// sits in an ordinary block mapping, only its own lines are rewritten and the rest of
+// the source is kept byte for byte. Anything more unusual falls back to encoding the
+// edited tree, which still keeps comments.
+func ReplacePlugin(source, name string, options *yaml.Node) (string, error) {
+	doc, err := ParseDocument(source)
+	if err != nil {
+		return "", err
+	}
+	indent := Indentation(doc)
+
+	// Work out where the plugin's lines are before the tree changes underneath us
+	span, spliceable := pluginSpan(source, doc, name)
+
+	if err := setPlugin(doc, name, options); err != nil {
+		return "", err
+	}
+
+	if spliceable {
+		if out, err := splicePlugin(source, span, name, options, indent); err == nil && sameConfig(out, doc) {
+			return out, nil
+		}
+	}
+	return Encode(doc, indent)
+}
+
+// Encode writes a node back out as YAML with the given indentation
+func Encode(node *yaml.Node, indent int) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Indentation guesses how many spaces a document indents nested mappings by, going
+// off the first nested block mapping it finds. It defaults to 2.
+func Indentation(doc *yaml.Node) int {
+	var find func(node *yaml.Node) int
+	find = func(node *yaml.Node) int {
+		switch node.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range node.Content {
+				if n := find(child); n > 0 {
+					return n
+				}
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				// Keys added by an edit have no position to go off
+				if key.Column > 0 && value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && len(value.Content) > 0 {
+					if n := value.Content[0].Column - key.Column; n > 0 {
+						return n
+					}
+				}
+				if n := find(value); n > 0 {
+					return n
+				}
+			}
+		}
+		return 0
+	}
+
+	if n := find(doc); n > 0 {
+		return n
+	}
+	return 2
+}
+
+// setPlugin does the node edit behind ReplacePlugin
+func setPlugin(doc *yaml.Node, name string, options *yaml.Node) error {
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	root := doc.Content[0]
+	if isNull(root) {
+		root.Kind, root.Tag, root.Value = yaml.MappingNode, "!!map", ""
+	}
+	if root.Kind != yaml.MappingNode {
+		return Error{Message: "config must be a mapping", Line: root.Line, Column: root.Column}
+	}
+
+	_, plugins := mappingEntry(root, "plugins")
+	if plugins == nil {
+		plugins = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		root.Content = append(root.Content, stringNode("plugins"), plugins)
+	}
+	if isNull(plugins) {
+		plugins.Kind, plugins.Tag, plugins.Value, plugins.Style = yaml.MappingNode, "!!map", "", 0
+	}
+	if plugins.Kind != yaml.MappingNode {
+		return Error{Path: "plugins", Message: "expected a mapping of plugin names to options", Line: plugins.Line, Column: plugins.Column}
+	}
+
+	for i := 0; i+1 < len(plugins.Content); i += 2 {
+		if plugins.Content[i].Value == name {
+			plugins.Content[i+1] = options
+			return nil
+		}
+	}
+	plugins.Content = append(plugins.Content, stringNode(name), options)
+	return nil
+}
+
+// lineSpan is a range of 0-based line indexes, end exclusive. For a plugin that
+// isn't in the config yet, start == end is where it gets inserted.
+type lineSpan struct {
+	start, end int
+	// indent is the leading whitespace of the plugin's key
+	indent string
+	// key is the plugin's existing key node, if it has one
+	key *yaml.Node
+}
+
+// pluginSpan finds the lines making up plugins.<name> in source, reporting false if
+// the layout is one we can't safely rewrite a few lines of
+func pluginSpan(source string, doc *yaml.Node, name string) (lineSpan, bool) {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return lineSpan{}, false
+	}
+	pluginsKey, plugins := mappingEntry(root, "plugins")
+	if plugins == nil || plugins.Kind != yaml.MappingNode || plugins.Style&yaml.FlowStyle != 0 || len(plugins.Content) == 0 {
+		return lineSpan{}, false
+	}
+
+	lines := strings.Split(source, "\n")
+
+	// The plugins mapping ends where the next top-level key starts, or at the end of the file
+	limit := len(lines)
+	if strings.HasSuffix(source, "\n") {
+		limit--
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i] == pluginsKey && i+2 < len(root.Content) {
+			limit = root.Content[i+2].Line - 1
+		}
+	}
+
+	// Each entry runs until the next sibling's key, less any blank or comment
+	// lines in between, which stay where they are
+	entryEnd := func(index int) int {
+		end := limit
+		if index+2 < len(plugins.Content) {
+			end = plugins.Content[index+2].Line - 1
+		}
+		start := plugins.Content[index].Line - 1
+		for end-1 > start {
+			trimmed := strings.TrimSpace(lines[end-1])
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				break
+			}
+			end--
+		}
+		return end
+	}
+
+	first := plugins.Content[0]
+	if !onOwnLine(lines, first) {
+		return lineSpan{}, false
+	}
+	indent := lines[first.Line-1][:first.Column-1]
+
+	for i := 0; i+1 < len(plugins.Content); i += 2 {
+		key := plugins.Content[i]
+		if key.Value != name {
+			continue
+		}
+		if !onOwnLine(lines, key) || key.Column != first.Column {
+			return lineSpan{}, false
+		}
+		return lineSpan{start: key.Line - 1, end: entryEnd(i), indent: indent, key: key}, true
+	}
+
+	// Not there yet, so it goes after the last plugin
+	end := entryEnd(len(plugins.Content) - 2)
+	return lineSpan{start: end, end: end, indent: indent}, true
+}
+
+// splicePlugin rewrites the lines in span to hold the plugin's new options
+func splicePlugin(source string, span lineSpan, name string, options *yaml.Node, indent int) (string, error) {
+	key := span.key
+	if key == nil {
+		key = stringNode(name)
+	}
+	keyText, err := Encode(&yaml.Node{Kind: key.Kind, Tag: key.Tag, Value: key.Value, Style: key.Style}, indent)
+	if err != nil {
+		return "", err
+	}
+	entry := span.indent + strings.TrimSuffix(keyText, "\n") + ":"
+
+	block := (options.Kind == yaml.MappingNode || options.Kind == yaml.SequenceNode) &&
+		options.Style&yaml.FlowStyle == 0 && len(options.Content) > 0
+	if block {
+		body, err := Encode(options, indent)
+		if err != nil {
+			return "", err
+		}
+		childIndent := span.indent + strings.Repeat(" ", indent)
+		for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+			if line == "" {
+				entry += "\n"
+			} else {
+				entry += "\n" + childIndent + line
+			}
+		}
+	} else {
+		value, err := Encode(options, indent)
+		if err != nil {
+			return "", err
+		}
+		if value = strings.TrimSuffix(value, "\n"); strings.Contains(value, "\n") {
+			return "", fmt.Errorf("can't inline multi-line value")
+		}
+		entry += " " + value
+	}
+
+	lines := strings.Split(source, "\n")
+	out := make([]string, 0, len(lines)+strings.Count(entry, "\n")+1)
+	out = append(out, lines[:span.start]...)
+	out = append(out, strings.Split(entry, "\n")...)
+	out = append(out, lines[span.end:]...)
+	return strings.Join(out, "\n"), nil
+}
+
+// sameConfig checks a spliced config parses to the same thing as the edited tree,
+// so a layout we misjudged never gets saved
+func sameConfig(source string, doc *yaml.Node) bool {
+	root, err := Parse(source)
+	if err != nil {
+		return false
+	}
+	return len(StructuralDiff(root, documentRoot(doc))) == 0
+}
+
+// onOwnLine reports whether nothing but whitespace comes before node on its line
+func onOwnLine(lines []string, node *yaml.Node) bool {
+	if node.Line < 1 || node.Line > len(lines) || node.Column < 1 || node.Column > len(lines[node.Line-1])+1 {
+		return false
+	}
+	return strings.TrimSpace(lines[node.Line-1][:node.Column-1]) == ""
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// mappingEntry returns the key and value nodes for key in a mapping, or nils
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,299 @@
+package configs
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// options parses the YAML for a plugin's options
+func options(t *testing.T, source string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(source), &doc); err != nil {
+		t.Fatalf("bad test options %q: %v", source, err)
+	}
+	return doc.Content[0]
+}
+
+const editSource = `# Our config
+prefix: "!"
+
+levels:
+  "106391128718245888": 100 # the owner
+
+plugins:
+  # Keep this first
+  utility:
+    config:
+      can_ping: true
+
+  automod:
+    config:
+      rules:
+        spam:
+          enabled: true # for the raid
+
+  # Last one
+  cases: {}
+`
+
+func TestReplacePlugin(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		plugin  string
+		options string
+		want    string
+	}{
+		{
+			name:    "replace in the middle",
+			source:  editSource,
+			plugin:  "automod",
+			options: "config:\n  rules: {}\n",
+			want: `# Our config
+prefix: "!"
+
+levels:
+  "106391128718245888": 100 # the owner
+
+plugins:
+  # Keep this first
+  utility:
+    config:
+      can_ping: true
+
+  automod:
+    config:
+      rules: {}
+
+  # Last one
+  cases: {}
+`,
+		},
+		{
+			name:    "replace the last",
+			source:  editSource,
+			plugin:  "cases",
+			options: "enabled: false\n",
+			want: `# Our config
+prefix: "!"
+
+levels:
+  "106391128718245888": 100 # the owner
+
+plugins:
+  # Keep this first
+  utility:
+    config:
+      can_ping: true
+
+  automod:
+    config:
+      rules:
+        spam:
+          enabled: true # for the raid
+
+  # Last one
+  cases:
+    enabled: false
+`,
+		},
+		{
+			name:    "add",
+			source:  editSource,
+			plugin:  "tags",
+			options: "config:\n  prefix: \"!!\"\n",
+			want: `# Our config
+prefix: "!"
+
+levels:
+  "106391128718245888": 100 # the owner
+
+plugins:
+  # Keep this first
+  utility:
+    config:
+      can_ping: true
+
+  automod:
+    config:
+      rules:
+        spam:
+          enabled: true # for the raid
+
+  # Last one
+  cases: {}
+  tags:
+    config:
+      prefix: "!!"
+`,
+		},
+		{
+			name:    "keeps comments in the new options",
+			source:  editSource,
+			plugin:  "utility",
+			options: "config:\n  can_ping: false # for now\n",
+			want: `# Our config
+prefix: "!"
+
+levels:
+  "106391128718245888": 100 # the owner
+
+plugins:
+  # Keep this first
+  utility:
+    config:
+      can_ping: false # for now
+
+  automod:
+    config:
+      rules:
+        spam:
+          enabled: true # for the raid
+
+  # Last one
+  cases: {}
+`,
+		},
+		{
+			name:    "keeps four space indents",
+			source:  "plugins:\n    utility:\n        enabled: true\n",
+			plugin:  "utility",
+			options: "config:\n  can_ping: true\n",
+			want:    "plugins:\n    utility:\n        config:\n            can_ping: true\n",
+		},
+		{
+			name:    "other keys after plugins",
+			source:  "plugins:\n  utility: {}\n\n# Levels\nlevels: {}\n",
+			plugin:  "automod",
+			options: "{}",
+			want:    "plugins:\n  utility: {}\n  automod: {}\n\n# Levels\nlevels: {}\n",
+		},
+		{
+			name:    "no plugins yet",
+			source:  "# Our config\nprefix: \"!\"\n",
+			plugin:  "utility",
+			options: "enabled: true\n",
+			want:    "# Our config\nprefix: \"!\"\nplugins:\n  utility:\n    enabled: true\n",
+		},
+		{
+			name:    "empty config",
+			source:  "",
+			plugin:  "utility",
+			options: "enabled: true\n",
+			want:    "plugins:\n  utility:\n    enabled: true\n",
+		},
+		{
+			name:    "flow style plugins",
+			source:  "prefix: \"!\" # the usual\nplugins: {utility: {enabled: true}}\n",
+			plugin:  "utility",
+			options: "enabled: false\n",
+			want:    "prefix: \"!\" # the usual\nplugins: {utility: {enabled: false}}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReplacePlugin(tt.source, tt.plugin, options(t, tt.options))
+			if err != nil {
+				t.Fatalf("ReplacePlugin() = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReplacePlugin() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacePluginRoundTrip(t *testing.T) {
+	doc, err := ParseDocument(editSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plugin := range []string{"utility", "automod", "cases"} {
+		t.Run(plugin, func(t *testing.T) {
+			got, err := ReplacePlugin(editSource, plugin, PluginOptions(doc, plugin))
+			if err != nil {
+				t.Fatalf("ReplacePlugin() = %v", err)
+			}
+			if got != editSource {
+				t.Errorf("putting back %s's own options changed the config to\n%s", plugin, got)
+			}
+		})
+	}
+}
+
+func TestReplacePluginErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   Error
+	}{
+		{
+			name:   "config not a mapping",
+			source: "- utility\n",
+			want:   Error{Message: "config must be a mapping", Line: 1, Column: 1},
+		},
+		{
+			name:   "plugins not a mapping",
+			source: "plugins: [utility]\n",
+			want:   Error{Path: "plugins", Message: "expected a mapping of plugin names to options", Line: 1, Column: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReplacePlugin(tt.source, "utility", options(t, "{}"))
+			if got, ok := err.(Error); !ok || got != tt.want {
+				t.Errorf("ReplacePlugin() = %#v, want %#v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginOptions(t *testing.T) {
+	doc, err := ParseDocument(editSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := PluginOptions(doc, "automod"); got == nil || got.Line != 14 {
+		t.Errorf("PluginOptions(automod) = %+v, want the mapping on line 14", got)
+	}
+	if got := PluginOptions(doc, "tags"); got != nil {
+		t.Errorf("PluginOptions(tags) = %+v, want nil", got)
+	}
+
+	empty, err := ParseDocument("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := PluginOptions(empty, "automod"); got != nil {
+		t.Errorf("PluginOptions() on an empty config = %+v, want nil", got)
+	}
+}
+
+func TestIndentation(t *testing.T) {
+	tests := []struct {
+		source string
+		want   int
+	}{
+		{"", 2},
+		{"prefix: \"!\"\n", 2},
+		{"plugins:\n    utility: {}\n", 4},
+		{"plugins: {utility: {}}\nlevels:\n   \"106391128718245888\": 100\n", 3},
+		{"list:\n  - a: 1\n    b:\n        c: 1\n", 4},
+	}
+
+	for _, tt := range tests {
+		doc, err := ParseDocument(tt.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Indentation(doc); got != tt.want {
+			t.Errorf("Indentation(%q) = %d, want %d", tt.source, got, tt.want)
+		}
+	}
+}
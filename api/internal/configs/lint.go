@@ -0,0 +1,224 @@
+package configs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxLevel is the highest level the bot's own defaults use
+const MaxLevel = 100
+
+// LintGuildConfig looks for things in a parsed guild config that are allowed but
+// probably a mistake. It only reports what ValidateGuildConfig doesn't, so the two
+// are meant to be used together.
+func LintGuildConfig(root *yaml.Node) []Error {
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil
+	}
+	l := &linter{}
+
+	_, levels := mappingEntry(root, "levels")
+	l.levels(levels)
+	l.duplicateIDs("", root)
+
+	if _, plugins := mappingEntry(root, "plugins"); plugins != nil && plugins.Kind == yaml.MappingNode {
+		forEachPair(plugins, func(key, value *yaml.Node) {
+			if GuildPlugins[key.Value] && value.Kind == yaml.MappingNode {
+				l.plugin("plugins."+key.Value, key, value)
+			}
+		})
+	}
+
+	return l.warnings
+}
+
+type linter struct {
+	warnings []Error
+	// reachable are the levels members can actually have: 0 for everyone
+	// without a level, plus each level handed out in levels
+	reachable []float64
+}
+
+func (l *linter) warn(path string, node *yaml.Node, format string, args ...any) {
+	l.warnings = append(l.warnings, Error{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+		Line:    node.Line,
+		Column:  node.Column,
+	})
+}
+
+func (l *linter) levels(node *yaml.Node) {
+	l.reachable = []float64{0}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+
+	forEachPair(node, func(key, value *yaml.Node) {
+		if !isNumber(value) {
+			return
+		}
+		level, err := strconv.ParseFloat(value.Value, 64)
+		if err != nil {
+			return
+		}
+		l.reachable = append(l.reachable, level)
+		if level > MaxLevel {
+			l.warn("levels."+key.Value, value, "level %s is above %d, the highest level the built-in permissions use", value.Value, MaxLevel)
+		}
+	})
+}
+
+func (l *linter) plugin(path string, key, node *yaml.Node) {
+	_, enabled := mappingEntry(node, "enabled")
+	_, config := mappingEntry(node, "config")
+	_, overrides := mappingEntry(node, "overrides")
+
+	if enabled != nil && isBool(enabled) && enabled.Value == "false" && (config != nil || overrides != nil) {
+		l.warn(path, key, "plugin is configured but not enabled, so its settings have no effect")
+	}
+
+	if overrides == nil || overrides.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, override := range overrides.Content {
+		if override.Kind == yaml.MappingNode {
+			l.overrideLevels(fmt.Sprintf("%s.overrides[%d]", path, i), override)
+		}
+	}
+}
+
+// overrideLevels warns about level criteria that no member can meet, looking
+// inside all/any as well. Criteria under not are skipped, since negating an
+// impossible condition is perfectly reachable.
+func (l *linter) overrideLevels(path string, criteria *yaml.Node) {
+	forEachPair(criteria, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "level":
+			conditions := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				conditions = value.Content
+			}
+			if !l.levelReachable(conditions) {
+				l.warn(path+".level", value, "no level in levels matches this, so the override only ever applies to the server owner")
+			}
+		case "all", "any":
+			if value.Kind != yaml.SequenceNode {
+				return
+			}
+			for i, sub := range value.Content {
+				if sub.Kind == yaml.MappingNode {
+					l.overrideLevels(fmt.Sprintf("%s.%s[%d]", path, key.Value, i), sub)
+				}
+			}
+		}
+	})
+}
+
+var levelConditionRegex = regexp.MustCompile(`^\s*(>=|<=|!=|>|<|=|!)?\s*(-?[0-9]+(?:\.[0-9]+)?)\s*$`)
+
+// levelReachable reports whether some reachable level meets every condition.
+// Conditions we can't read are assumed to be reachable, the validator in the bot
+// has the final say on those.
+func (l *linter) levelReachable(conditions []*yaml.Node) bool {
+	for _, level := range l.reachable {
+		matchesAll := true
+		for _, condition := range conditions {
+			m := levelConditionRegex.FindStringSubmatch(condition.Value)
+			if condition.Kind != yaml.ScalarNode || m == nil {
+				return true
+			}
+			target, _ := strconv.ParseFloat(m[2], 64)
+			if !compareLevel(m[1], level, target) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			return true
+		}
+	}
+	return false
+}
+
+func compareLevel(op string, level, target float64) bool {
+	switch op {
+	case ">":
+		return level > target
+	case "<":
+		return level < target
+	case "<=":
+		return level <= target
+	case "=":
+		return level == target
+	case "!", "!=":
+		return level != target
+	}
+	// A bare number means "at least"
+	return level >= target
+}
+
+// duplicateIDs walks the whole config looking for lists that name the same role,
+// channel or user ID more than once
+func (l *linter) duplicateIDs(path string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		forEachPair(node, func(key, value *yaml.Node) {
+			l.duplicateIDs(joinPath(path, key.Value), value)
+		})
+	case yaml.SequenceNode:
+		seen := map[string]bool{}
+		for i, item := range node.Content {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if item.Kind != yaml.ScalarNode {
+				l.duplicateIDs(itemPath, item)
+				continue
+			}
+			if !snowflakeRegex.MatchString(item.Value) {
+				continue
+			}
+			if seen[item.Value] {
+				l.warn(itemPath, item, "%s is listed more than once", item.Value)
+			}
+			seen[item.Value] = true
+		}
+	}
+}
+
+// closestPlugin suggests the name in plugins nearest to a misspelt one, or "" if
+// nothing is close enough to be a likely typo
+func closestPlugin(name string, plugins map[string]bool) string {
+	name = strings.ToLower(name)
+	best, bestDistance := "", 3
+	for plugin := range plugins {
+		if d := editDistance(name, plugin); d < bestDistance || (d == bestDistance && best != "" && plugin < best) {
+			best, bestDistance = plugin, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
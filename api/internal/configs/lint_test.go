@@ -0,0 +1,100 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintGuildConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []Error
+	}{
+		{
+			name:   "empty",
+			source: "",
+		},
+		{
+			name: "nothing to warn about",
+			source: "levels:\n  \"106391128718245888\": 100\n  \"108552944961454080\": 50\n" +
+				"plugins:\n  automod:\n    enabled: true\n    overrides:\n      - level: \">=50\"\n        config: {}\n" +
+				"      - level: [\">0\", \"<100\"]\n        config: {}\n      - not:\n          level: \">=1000\"\n        config: {}\n" +
+				"  utility:\n    enabled: false\n",
+		},
+		{
+			name:   "level above the highest built-in",
+			source: "levels:\n  \"106391128718245888\": 1000\n  \"108552944961454080\": 100\n",
+			want: []Error{
+				{Path: "levels.106391128718245888", Message: "level 1000 is above 100, the highest level the built-in permissions use", Line: 2, Column: 25},
+			},
+		},
+		{
+			name:   "configured but not enabled",
+			source: "plugins:\n  automod:\n    enabled: false\n    config:\n      rules: {}\n",
+			want: []Error{
+				{Path: "plugins.automod", Message: "plugin is configured but not enabled, so its settings have no effect", Line: 2, Column: 3},
+			},
+		},
+		{
+			name: "unreachable levels",
+			source: "levels:\n  \"106391128718245888\": 50\n" +
+				"plugins:\n  automod:\n    overrides:\n" +
+				"      - level: \">=100\"\n        config: {}\n" +
+				"      - level: [\">0\", \"<50\"]\n        config: {}\n" +
+				"      - all:\n          - level: 60\n        config: {}\n" +
+				"      - level: \"=50\"\n        config: {}\n" +
+				"      - level: \"<=0\"\n        config: {}\n",
+			want: []Error{
+				{Path: "plugins.automod.overrides[0].level", Message: "no level in levels matches this, so the override only ever applies to the server owner", Line: 6, Column: 16},
+				{Path: "plugins.automod.overrides[1].level", Message: "no level in levels matches this, so the override only ever applies to the server owner", Line: 8, Column: 16},
+				{Path: "plugins.automod.overrides[2].all[0].level", Message: "no level in levels matches this, so the override only ever applies to the server owner", Line: 11, Column: 20},
+			},
+		},
+		{
+			name:   "unreadable level",
+			source: "plugins:\n  automod:\n    overrides:\n      - level: \"high\"\n        config: {}\n",
+		},
+		{
+			name: "duplicate IDs",
+			source: "plugins:\n  automod:\n    config:\n      rules:\n        spam:\n" +
+				"          roles: [\"106391128718245888\", \"108552944961454080\", \"106391128718245888\"]\n" +
+				"          names: [a, a]\n",
+			want: []Error{
+				{Path: "plugins.automod.config.rules.spam.roles[2]", Message: "106391128718245888 is listed more than once", Line: 6, Column: 63},
+			},
+		},
+		{
+			name:   "unknown plugins are left to validation",
+			source: "plugins:\n  automd:\n    enabled: false\n    config: {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validate(t, tt.source, LintGuildConfig)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LintGuildConfig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosestPlugin(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"automod", "automod"},
+		{"AutoMod", "automod"},
+		{"automd", "automod"},
+		{"mod_action", "mod_actions"},
+		{"tag", "tags"},
+		{"something_else", ""},
+	}
+	for _, tt := range tests {
+		if got := closestPlugin(tt.name, GuildPlugins); got != tt.want {
+			t.Errorf("closestPlugin(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PatchOperation is a single RFC 6902 JSON Patch operation
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyPatch applies a JSON Patch to a parsed document in place. The operations
+// are applied in order and stop at the first that fails, which is returned as an
+// Error pointing at the operation. Nodes that are replaced keep their comments.
+// The document is held to the same size limits as a parsed config after every
+// operation, since a handful of copies can otherwise grow it exponentially.
+func ApplyPatch(doc *yaml.Node, ops []PatchOperation) error {
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if root := doc.Content[0]; isNull(root) {
+		root.Kind, root.Tag, root.Value = yaml.MappingNode, "!!map", ""
+	}
+
+	for i, op := range ops {
+		if err := applyOperation(doc, op); err != nil {
+			return Error{Path: fmt.Sprintf("patch[%d]", i), Message: fmt.Sprintf("%s %q: %s", op.Op, op.Path, err)}
+		}
+		// Removing and testing can't make the document any bigger
+		if op.Op == "remove" || op.Op == "test" {
+			continue
+		}
+		if err := checkSafety(doc); err != nil {
+			return Error{Path: fmt.Sprintf("patch[%d]", i), Message: fmt.Sprintf("%s %q: %s", op.Op, op.Path, err.(Error).Message)}
+		}
+	}
+	return nil
+}
+
+func applyOperation(doc *yaml.Node, op PatchOperation) error {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return fmt.Errorf("missing value")
+		}
+		value, err := jsonToNode(op.Value)
+		if err != nil {
+			return err
+		}
+		switch op.Op {
+		case "add":
+			return addNode(doc, path, value)
+		case "replace":
+			return replaceNode(doc, path, value)
+		}
+		current, err := getNode(doc, path)
+		if err != nil {
+			return err
+		}
+		if !sameValue(current, value) {
+			return fmt.Errorf("test failed")
+		}
+		return nil
+
+	case "remove":
+		_, err := removeNode(doc, path)
+		return err
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		if op.Op == "move" {
+			// Copies are taken before they're added, so only a move can end up
+			// inside itself
+			if len(from) < len(path) && slices.Equal(from, path[:len(from)]) {
+				return fmt.Errorf("can't move a value inside itself")
+			}
+			value, err := removeNode(doc, from)
+			if err != nil {
+				return fmt.Errorf("from: %w", err)
+			}
+			return addNode(doc, path, value)
+		}
+		value, err := getNode(doc, from)
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		return addNode(doc, path, copyNode(value))
+	}
+
+	return fmt.Errorf("unknown operation")
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path must start with /")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// getNode returns the node at path
+func getNode(doc *yaml.Node, path []string) (*yaml.Node, error) {
+	node := doc.Content[0]
+	for i, token := range path {
+		child, err := childNode(node, token)
+		if err != nil {
+			return nil, fmt.Errorf("/%s: %w", strings.Join(path[:i+1], "/"), err)
+		}
+		node = child
+	}
+	return node, nil
+}
+
+func childNode(node *yaml.Node, token string) (*yaml.Node, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if _, value := mappingEntry(node, token); value != nil {
+			return value, nil
+		}
+		return nil, fmt.Errorf("no such key")
+	case yaml.SequenceNode:
+		i, err := sequenceIndex(node, token, false)
+		if err != nil {
+			return nil, err
+		}
+		return node.Content[i], nil
+	}
+	return nil, fmt.Errorf("not a mapping or list")
+}
+
+// sequenceIndex parses a list index token. With forAdd, the index one past the
+// end (or "-") is allowed too.
+func sequenceIndex(node *yaml.Node, token string, forAdd bool) (int, error) {
+	if forAdd && token == "-" {
+		return len(node.Content), nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid list index %q", token)
+	}
+	if i > len(node.Content) || (i == len(node.Content) && !forAdd) {
+		return 0, fmt.Errorf("list index %d out of range", i)
+	}
+	return i, nil
+}
+
+func addNode(doc *yaml.Node, path []string, value *yaml.Node) error {
+	if len(path) == 0 {
+		doc.Content[0] = value
+		return nil
+	}
+	parent, err := getNode(doc, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	token := path[len(path)-1]
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == token {
+				parent.Content[i+1] = keepComments(parent.Content[i+1], value)
+				return nil
+			}
+		}
+		parent.Content = append(parent.Content, stringNode(token), value)
+		return nil
+	case yaml.SequenceNode:
+		i, err := sequenceIndex(parent, token, true)
+		if err != nil {
+			return err
+		}
+		parent.Content = append(parent.Content[:i], append([]*yaml.Node{value}, parent.Content[i:]...)...)
+		return nil
+	}
+	return fmt.Errorf("parent is not a mapping or list")
+}
+
+func replaceNode(doc *yaml.Node, path []string, value *yaml.Node) error {
+	if len(path) == 0 {
+		doc.Content[0] = keepComments(doc.Content[0], value)
+		return nil
+	}
+	parent, err := getNode(doc, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	token := path[len(path)-1]
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == token {
+				parent.Content[i+1] = keepComments(parent.Content[i+1], value)
+				return nil
+			}
+		}
+		return fmt.Errorf("no such key")
+	case yaml.SequenceNode:
+		i, err := sequenceIndex(parent, token, false)
+		if err != nil {
+			return err
+		}
+		parent.Content[i] = keepComments(parent.Content[i], value)
+		return nil
+	}
+	return fmt.Errorf("parent is not a mapping or list")
+}
+
+// removeNode takes the node at path out of the document and returns it
+func removeNode(doc *yaml.Node, path []string) (*yaml.Node, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("can't remove the whole config")
+	}
+	parent, err := getNode(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == token {
+				value := parent.Content[i+1]
+				parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("no such key")
+	case yaml.SequenceNode:
+		i, err := sequenceIndex(parent, token, false)
+		if err != nil {
+			return nil, err
+		}
+		value := parent.Content[i]
+		parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+		return value, nil
+	}
+	return nil, fmt.Errorf("parent is not a mapping or list")
+}
+
+// keepComments moves the comments around a node that's being replaced onto its
+// replacement, so "enabled: true # for the raid" keeps its note when flipped
+func keepComments(old, replacement *yaml.Node) *yaml.Node {
+	if replacement.HeadComment == "" {
+		replacement.HeadComment = old.HeadComment
+	}
+	if replacement.LineComment == "" {
+		replacement.LineComment = old.LineComment
+	}
+	if replacement.FootComment == "" {
+		replacement.FootComment = old.FootComment
+	}
+	return replacement
+}
+
+// jsonToNode turns a JSON value into a node tree. Numbers are kept exactly as
+// written, so snowflakes don't get rounded through a float.
+func jsonToNode(raw json.RawMessage) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	return valueNode(value), nil
+}
+
+func valueNode(value any) *yaml.Node {
+	switch v := value.(type) {
+	case map[string]any:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// Go maps have no order, so sort to keep the output stable
+		slices.Sort(keys)
+		for _, key := range keys {
+			node.Content = append(node.Content, stringNode(key), valueNode(v[key]))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, valueNode(item))
+		}
+		return node
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v.String()}
+	case string:
+		return stringNode(v)
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// sameValue compares two nodes the way JSON Patch's test operation does, by value
+func sameValue(a, b *yaml.Node) bool {
+	aJSON, err := json.Marshal(decode(a))
+	if err != nil {
+		return false
+	}
+	bJSON, err := json.Marshal(decode(b))
+	if err != nil {
+		return false
+	}
+
+	var aValue, bValue any
+	if json.Unmarshal(aJSON, &aValue) != nil || json.Unmarshal(bJSON, &bValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+func copyNode(node *yaml.Node) *yaml.Node {
+	out := *node
+	out.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		out.Content[i] = copyNode(child)
+	}
+	return &out
+}
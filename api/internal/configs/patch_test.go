@@ -0,0 +1,268 @@
+package configs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// patch applies a JSON Patch, given as JSON, to source and returns the result
+func patch(t *testing.T, source, ops string) (string, error) {
+	t.Helper()
+	doc, err := ParseDocument(source)
+	if err != nil {
+		t.Fatalf("ParseDocument() = %v", err)
+	}
+	var operations []PatchOperation
+	if err := json.Unmarshal([]byte(ops), &operations); err != nil {
+		t.Fatalf("bad test patch %s: %v", ops, err)
+	}
+	if err := ApplyPatch(doc, operations); err != nil {
+		return "", err
+	}
+	out, err := Encode(doc, Indentation(doc))
+	if err != nil {
+		t.Fatalf("Encode() = %v", err)
+	}
+	return out, nil
+}
+
+const patchSource = `# Our config
+prefix: "!"
+levels:
+  "106391128718245888": 100
+plugins:
+  automod:
+    config:
+      rules:
+        spam:
+          enabled: true # for the raid
+  tags:
+    config:
+      categories: [a, b]
+`
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  string
+		want string
+		err  string
+	}{
+		{
+			name: "no operations",
+			ops:  `[]`,
+			want: patchSource,
+		},
+		{
+			name: "replace keeps comments",
+			ops:  `[{"op": "replace", "path": "/plugins/automod/config/rules/spam/enabled", "value": false}]`,
+			want: strings.Replace(patchSource, "enabled: true # for the raid", "enabled: false # for the raid", 1),
+		},
+		{
+			name: "add a key",
+			ops:  `[{"op": "add", "path": "/plugins/utility", "value": {"enabled": true}}]`,
+			want: patchSource + "  utility:\n    enabled: true\n",
+		},
+		{
+			name: "add replaces an existing key",
+			ops:  `[{"op": "add", "path": "/prefix", "value": "?"}]`,
+			want: strings.Replace(patchSource, `prefix: "!"`, `prefix: '?'`, 1),
+		},
+		{
+			name: "add to a list",
+			ops:  `[{"op": "add", "path": "/plugins/tags/config/categories/1", "value": "c"}, {"op": "add", "path": "/plugins/tags/config/categories/-", "value": "d"}]`,
+			want: strings.Replace(patchSource, "[a, b]", "[a, c, b, d]", 1),
+		},
+		{
+			name: "snowflakes stay exact",
+			ops:  `[{"op": "add", "path": "/plugins/tags/config/user", "value": 106391128718245888}]`,
+			want: patchSource + "      user: 106391128718245888\n",
+		},
+		{
+			name: "escaped keys",
+			ops:  `[{"op": "add", "path": "/a~1b~0c", "value": 1}]`,
+			want: patchSource + "a/b~c: 1\n",
+		},
+		{
+			name: "remove",
+			ops:  `[{"op": "remove", "path": "/plugins/tags"}, {"op": "remove", "path": "/levels/106391128718245888"}]`,
+			want: "# Our config\nprefix: \"!\"\nlevels: {}\nplugins:\n  automod:\n    config:\n      rules:\n        spam:\n          enabled: true # for the raid\n",
+		},
+		{
+			name: "move",
+			ops:  `[{"op": "move", "from": "/plugins/tags", "path": "/plugins/custom_tags"}]`,
+			want: strings.Replace(patchSource, "  tags:", "  custom_tags:", 1),
+		},
+		{
+			name: "copy",
+			ops:  `[{"op": "copy", "from": "/levels", "path": "/plugins/automod/levels"}]`,
+			want: strings.Replace(patchSource, "  tags:\n", "    levels:\n      \"106391128718245888\": 100\n  tags:\n", 1),
+		},
+		{
+			name: "passing test",
+			ops:  `[{"op": "test", "path": "/plugins/tags/config", "value": {"categories": ["a", "b"]}}, {"op": "test", "path": "/levels/106391128718245888", "value": 100}]`,
+			want: patchSource,
+		},
+		{
+			name: "failing test stops the patch",
+			ops:  `[{"op": "replace", "path": "/prefix", "value": "?"}, {"op": "test", "path": "/plugins/automod/config/rules/spam/enabled", "value": false}, {"op": "remove", "path": "/plugins"}]`,
+			err:  `patch[1]: test "/plugins/automod/config/rules/spam/enabled": test failed`,
+		},
+		{
+			name: "test compares types",
+			ops:  `[{"op": "test", "path": "/levels/106391128718245888", "value": "100"}]`,
+			err:  `patch[0]: test "/levels/106391128718245888": test failed`,
+		},
+		{
+			name: "replace a missing key",
+			ops:  `[{"op": "replace", "path": "/plugins/utility", "value": {}}]`,
+			err:  `patch[0]: replace "/plugins/utility": no such key`,
+		},
+		{
+			name: "remove a missing key",
+			ops:  `[{"op": "remove", "path": "/plugins/utility/config"}]`,
+			err:  `patch[0]: remove "/plugins/utility/config": /plugins/utility: no such key`,
+		},
+		{
+			name: "remove the whole config",
+			ops:  `[{"op": "remove", "path": ""}]`,
+			err:  `patch[0]: remove "": can't remove the whole config`,
+		},
+		{
+			name: "list index out of range",
+			ops:  `[{"op": "add", "path": "/plugins/tags/config/categories/3", "value": "c"}]`,
+			err:  `patch[0]: add "/plugins/tags/config/categories/3": list index 3 out of range`,
+		},
+		{
+			name: "leading zero index",
+			ops:  `[{"op": "replace", "path": "/plugins/tags/config/categories/01", "value": "c"}]`,
+			err:  `patch[0]: replace "/plugins/tags/config/categories/01": invalid list index "01"`,
+		},
+		{
+			name: "into a scalar",
+			ops:  `[{"op": "add", "path": "/prefix/x", "value": 1}]`,
+			err:  `patch[0]: add "/prefix/x": parent is not a mapping or list`,
+		},
+		{
+			name: "missing value",
+			ops:  `[{"op": "add", "path": "/x"}]`,
+			err:  `patch[0]: add "/x": missing value`,
+		},
+		{
+			name: "bad path",
+			ops:  `[{"op": "add", "path": "x", "value": 1}]`,
+			err:  `patch[0]: add "x": path must start with /`,
+		},
+		{
+			name: "missing from",
+			ops:  `[{"op": "copy", "from": "/nothing", "path": "/x"}]`,
+			err:  `patch[0]: copy "/x": from: /nothing: no such key`,
+		},
+		{
+			name: "unknown operation",
+			ops:  `[{"op": "merge", "path": "/x", "value": 1}]`,
+			err:  `patch[0]: merge "/x": unknown operation`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patch(t, patchSource, tt.ops)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ApplyPatch() = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyPatch() = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchEmptyConfig(t *testing.T) {
+	got, err := patch(t, "", `[{"op": "add", "path": "/plugins", "value": {"utility": {}}}]`)
+	if err != nil {
+		t.Fatalf("ApplyPatch() = %v", err)
+	}
+	if want := "plugins:\n  utility: {}\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApplyPatchIntoItself(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  string
+		want string
+		err  string
+	}{
+		{
+			name: "copy into itself",
+			ops:  `[{"op": "copy", "from": "/a", "path": "/a/b"}]`,
+			want: "a:\n  x: 1\n  b:\n    x: 1\n",
+		},
+		{
+			name: "copy the root into itself",
+			ops:  `[{"op": "copy", "from": "", "path": "/b"}]`,
+			want: "a:\n  x: 1\nb:\n  a:\n    x: 1\n",
+		},
+		{
+			name: "move into itself",
+			ops:  `[{"op": "move", "from": "/a", "path": "/a/b"}]`,
+			err:  `patch[0]: move "/a/b": can't move a value inside itself`,
+		},
+		{
+			name: "move the root into itself",
+			ops:  `[{"op": "move", "from": "", "path": "/b"}]`,
+			err:  `patch[0]: move "/b": can't move a value inside itself`,
+		},
+		{
+			name: "move onto itself",
+			ops:  `[{"op": "move", "from": "/a", "path": "/a"}]`,
+			want: "a:\n  x: 1\n",
+		},
+		{
+			// Only a whole token counts as the same key
+			name: "move next to itself",
+			ops:  `[{"op": "move", "from": "/a", "path": "/ab"}]`,
+			want: "ab:\n  x: 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patch(t, "a:\n  x: 1\n", tt.ops)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ApplyPatch() = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyPatch() = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchSizeLimit(t *testing.T) {
+	// Each copy doubles the document, so it passes MaxNodes well before the end
+	ops := []string{`{"op": "add", "path": "/a", "value": [1, 2, 3, 4]}`}
+	for i := 0; i < 40; i++ {
+		ops = append(ops, `{"op": "copy", "from": "/a", "path": "/a/-"}`)
+	}
+
+	_, err := patch(t, "", "["+strings.Join(ops, ",")+"]")
+	if err == nil || !strings.Contains(err.Error(), "config is too large") {
+		t.Fatalf("ApplyPatch() = %v, want the config to be too large", err)
+	}
+}
@@ -0,0 +1,67 @@
+package configs
+
+// GuildPlugins are the plugin names a guild config may configure. Keep in sync
+// with availableGuildPlugins in backend/src/plugins/availablePlugins.ts.
+var GuildPlugins = map[string]bool{
+	"auto_delete":          true,
+	"automod":              true,
+	"auto_reactions":       true,
+	"cases":                true,
+	"censor":               true,
+	"command_aliases":      true,
+	"companion_channels":   true,
+	"context_menu":         true,
+	"counters":             true,
+	"custom_events":        true,
+	"guild_info_saver":     true,
+	"internal_poster":      true,
+	"locate_user":          true,
+	"logs":                 true,
+	"message_saver":        true,
+	"mod_actions":          true,
+	"mutes":                true,
+	"name_history":         true,
+	"persist":              true,
+	"phisherman":           true,
+	"pingable_roles":       true,
+	"post":                 true,
+	"reaction_roles":       true,
+	"reminders":            true,
+	"role_buttons":         true,
+	"role_manager":         true,
+	"roles":                true,
+	"self_grantable_roles": true,
+	"slowmode":             true,
+	"spam":                 true,
+	"starboard":            true,
+	"tags":                 true,
+	"time_and_date":        true,
+	"username_saver":       true,
+	"utility":              true,
+	"welcome_message":      true,
+	"common":               true,
+}
+
+// GlobalPlugins are the plugin names the global config may configure. Keep in sync
+// with availableGlobalPlugins in backend/src/plugins/availablePlugins.ts.
+var GlobalPlugins = map[string]bool{
+	"guild_config_reloader": true,
+	"bot_control":           true,
+	"guild_access_monitor":  true,
+}
+
+// overrideCriteria are the keys an override may match on, besides its config
+var overrideCriteria = map[string]bool{
+	"level":     true,
+	"channel":   true,
+	"category":  true,
+	"thread":    true,
+	"thread_id": true,
+	"is_thread": true,
+	"role":      true,
+	"user":      true,
+	"any":       true,
+	"all":       true,
+	"not":       true,
+	"extra":     true,
+}
@@ -0,0 +1,116 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReferenceKind is what sort of Discord object a snowflake in a config points at
+type ReferenceKind string
+
+const (
+	ReferenceRole    ReferenceKind = "role"
+	ReferenceChannel ReferenceKind = "channel"
+	ReferenceUser    ReferenceKind = "user"
+	// ReferenceRoleOrUser is used for levels, which are keyed by either
+	ReferenceRoleOrUser ReferenceKind = "role or user"
+)
+
+// Reference is a snowflake found somewhere in a config, along with where it is
+type Reference struct {
+	ID     string
+	Kind   ReferenceKind
+	Path   string
+	Line   int
+	Column int
+}
+
+// ExtractReferences finds the role, channel and user IDs in a parsed guild config.
+// Under plugins, a key says what its IDs are by name: role/roles/mute_role and the
+// like hold roles, channel/log_channel/categories hold channels, and user/users hold
+// users. The IDs can be a single value, a list, or the keys of a mapping (as in the
+// logs plugin's channels). Anything that isn't a snowflake, such as a role name, is
+// skipped.
+func ExtractReferences(root *yaml.Node) []Reference {
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil
+	}
+	var refs []Reference
+
+	if _, levels := mappingEntry(root, "levels"); levels != nil && levels.Kind == yaml.MappingNode {
+		forEachPair(levels, func(key, _ *yaml.Node) {
+			refs = appendReference(refs, key, ReferenceRoleOrUser, "levels."+key.Value)
+		})
+	}
+
+	if _, plugins := mappingEntry(root, "plugins"); plugins != nil && plugins.Kind == yaml.MappingNode {
+		forEachPair(plugins, func(key, value *yaml.Node) {
+			refs = findReferences(refs, "plugins."+key.Value, value)
+		})
+	}
+
+	return refs
+}
+
+func findReferences(refs []Reference, path string, node *yaml.Node) []Reference {
+	switch node.Kind {
+	case yaml.MappingNode:
+		forEachPair(node, func(key, value *yaml.Node) {
+			keyPath := path + "." + key.Value
+			kind, ok := referenceKind(key.Value)
+			if !ok {
+				refs = findReferences(refs, keyPath, value)
+				return
+			}
+
+			switch value.Kind {
+			case yaml.ScalarNode:
+				refs = appendReference(refs, value, kind, keyPath)
+			case yaml.SequenceNode:
+				for i, item := range value.Content {
+					refs = appendReference(refs, item, kind, fmt.Sprintf("%s[%d]", keyPath, i))
+				}
+			case yaml.MappingNode:
+				forEachPair(value, func(idKey, idValue *yaml.Node) {
+					if snowflakeRegex.MatchString(idKey.Value) {
+						refs = appendReference(refs, idKey, kind, keyPath+"."+idKey.Value)
+					}
+					refs = findReferences(refs, keyPath+"."+idKey.Value, idValue)
+				})
+			}
+		})
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			refs = findReferences(refs, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	}
+	return refs
+}
+
+// referenceKind works out from a key's name what its IDs refer to
+func referenceKind(key string) (ReferenceKind, bool) {
+	for _, kind := range []struct {
+		kind  ReferenceKind
+		names []string
+	}{
+		{ReferenceRole, []string{"role", "roles"}},
+		{ReferenceChannel, []string{"channel", "channels", "category", "categories"}},
+		{ReferenceUser, []string{"user", "users"}},
+	} {
+		for _, name := range kind.names {
+			if key == name || strings.HasSuffix(key, "_"+name) {
+				return kind.kind, true
+			}
+		}
+	}
+	return "", false
+}
+
+func appendReference(refs []Reference, node *yaml.Node, kind ReferenceKind, path string) []Reference {
+	if node.Kind != yaml.ScalarNode || !snowflakeRegex.MatchString(node.Value) {
+		return refs
+	}
+	return append(refs, Reference{ID: node.Value, Kind: kind, Path: path, Line: node.Line, Column: node.Column})
+}
@@ -0,0 +1,114 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractReferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []Reference
+	}{
+		{
+			name:   "empty",
+			source: "",
+		},
+		{
+			name:   "levels",
+			source: "levels:\n  \"106391128718245888\": 100\n  everyone: 0\n",
+			want: []Reference{
+				{ID: "106391128718245888", Kind: ReferenceRoleOrUser, Path: "levels.106391128718245888", Line: 2, Column: 3},
+			},
+		},
+		{
+			name: "single values",
+			source: "plugins:\n  mutes:\n    config:\n" +
+				"      mute_role: \"106391128718245888\"\n" +
+				"      log_channel: \"108552944961454080\"\n" +
+				"      user: \"108552944961454081\"\n",
+			want: []Reference{
+				{ID: "106391128718245888", Kind: ReferenceRole, Path: "plugins.mutes.config.mute_role", Line: 4, Column: 18},
+				{ID: "108552944961454080", Kind: ReferenceChannel, Path: "plugins.mutes.config.log_channel", Line: 5, Column: 20},
+				{ID: "108552944961454081", Kind: ReferenceUser, Path: "plugins.mutes.config.user", Line: 6, Column: 13},
+			},
+		},
+		{
+			name: "lists",
+			source: "plugins:\n  automod:\n    config:\n      rules:\n        spam:\n" +
+				"          ignored_roles: [\"106391128718245888\", Moderators]\n" +
+				"          categories:\n            - \"108552944961454080\"\n",
+			want: []Reference{
+				{ID: "106391128718245888", Kind: ReferenceRole, Path: "plugins.automod.config.rules.spam.ignored_roles[0]", Line: 6, Column: 27},
+				{ID: "108552944961454080", Kind: ReferenceChannel, Path: "plugins.automod.config.rules.spam.categories[0]", Line: 8, Column: 15},
+			},
+		},
+		{
+			name: "mapping keys",
+			source: "plugins:\n  logs:\n    config:\n      channels:\n" +
+				"        \"106391128718245888\":\n          include: [MEMBER_BAN]\n          excluded_users: [\"108552944961454080\"]\n" +
+				"        not_an_id: {}\n",
+			want: []Reference{
+				{ID: "106391128718245888", Kind: ReferenceChannel, Path: "plugins.logs.config.channels.106391128718245888", Line: 5, Column: 9},
+				{ID: "108552944961454080", Kind: ReferenceUser, Path: "plugins.logs.config.channels.106391128718245888.excluded_users[0]", Line: 7, Column: 28},
+			},
+		},
+		{
+			name: "overrides",
+			source: "plugins:\n  automod:\n    overrides:\n" +
+				"      - channel: \"106391128718245888\"\n        role: [\"108552944961454080\"]\n        config: {}\n",
+			want: []Reference{
+				{ID: "106391128718245888", Kind: ReferenceChannel, Path: "plugins.automod.overrides[0].channel", Line: 4, Column: 18},
+				{ID: "108552944961454080", Kind: ReferenceRole, Path: "plugins.automod.overrides[0].role[0]", Line: 5, Column: 16},
+			},
+		},
+		{
+			name:   "unrelated keys",
+			source: "plugins:\n  tags:\n    config:\n      message_id: \"106391128718245888\"\n      controller: \"108552944961454080\"\n",
+		},
+		{
+			name:   "outside plugins",
+			source: "prefix: \"106391128718245888\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			if got := ExtractReferences(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractReferences() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceKind(t *testing.T) {
+	tests := []struct {
+		key  string
+		kind ReferenceKind
+		ok   bool
+	}{
+		{"role", ReferenceRole, true},
+		{"mute_role", ReferenceRole, true},
+		{"ignored_roles", ReferenceRole, true},
+		{"channel", ReferenceChannel, true},
+		{"log_channels", ReferenceChannel, true},
+		{"category", ReferenceChannel, true},
+		{"categories", ReferenceChannel, true},
+		{"users", ReferenceUser, true},
+		{"excluded_user", ReferenceUser, true},
+		{"controller", "", false},
+		{"userlist", "", false},
+		{"username", "", false},
+	}
+	for _, tt := range tests {
+		kind, ok := referenceKind(tt.key)
+		if kind != tt.kind || ok != tt.ok {
+			t.Errorf("referenceKind(%q) = %q, %v, want %q, %v", tt.key, kind, ok, tt.kind, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package configs
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MaxDepth is how deeply a config may nest mappings and sequences
+	MaxDepth = 64
+	// MaxNodes is how many YAML nodes a single config may contain
+	MaxNodes = 250_000
+)
+
+// checkSafety is our take on validateNoObjectAliases in the backend. Anchors and
+// aliases on mappings and sequences are refused, because they allow alias bombs
+// and make a change in one spot silently apply to another. Scalar anchors are
+// harmless and stay allowed. It also caps the size of the document, so we don't
+// walk an absurd tree later on.
+func checkSafety(doc *yaml.Node) error {
+	nodes := 0
+
+	var walk func(node *yaml.Node, depth int) error
+	walk = func(node *yaml.Node, depth int) error {
+		nodes++
+		if nodes > MaxNodes {
+			return Error{Message: fmt.Sprintf("config is too large (more than %d values)", MaxNodes), Line: node.Line, Column: node.Column}
+		}
+		if depth > MaxDepth {
+			return Error{Message: fmt.Sprintf("config is nested too deeply (more than %d levels)", MaxDepth), Line: node.Line, Column: node.Column}
+		}
+
+		switch node.Kind {
+		case yaml.AliasNode:
+			if node.Alias != nil && node.Alias.Kind != yaml.ScalarNode {
+				return Error{Message: "Object aliases are not allowed", Line: node.Line, Column: node.Column}
+			}
+			return nil
+		case yaml.MappingNode, yaml.SequenceNode:
+			if node.Anchor != "" {
+				return Error{Message: "Object anchors are not allowed", Line: node.Line, Column: node.Column}
+			}
+		}
+
+		for _, child := range node.Content {
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return walk(doc, 0)
+}
+
+// checkDuplicateKeys refuses mappings that define the same key twice. yaml.v3 lets
+// them through, but js-yaml in the bot doesn't, so the config would never load.
+func checkDuplicateKeys(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		seen := make(map[string]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				continue
+			}
+			if seen[key.Value] {
+				return Error{Message: fmt.Sprintf("duplicated mapping key %q", key.Value), Line: key.Line, Column: key.Column}
+			}
+			seen[key.Value] = true
+		}
+	}
+
+	for _, child := range node.Content {
+		if err := checkDuplicateKeys(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
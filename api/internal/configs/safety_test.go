@@ -0,0 +1,66 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseSafety(t *testing.T) {
+	// The classic billion laughs: each level refers to the one before ten times
+	bomb := "a: &a [\"lol\", \"lol\", \"lol\", \"lol\", \"lol\", \"lol\", \"lol\", \"lol\", \"lol\"]\n"
+	for i := 'b'; i <= 'i'; i++ {
+		prev := string(i - 1)
+		bomb += fmt.Sprintf("%c: &%c [*%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s]\n", i, i, prev, prev, prev, prev, prev, prev, prev, prev, prev)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		want   *Error
+	}{
+		{
+			name:   "alias bomb",
+			source: bomb,
+			want:   &Error{Message: "Object anchors are not allowed", Line: 1, Column: 4},
+		},
+		{
+			name:   "mapping alias",
+			source: "plugins:\n  automod:\n    config: &shared\n      rules: {}\n  spam:\n    config: *shared\n",
+			want:   &Error{Message: "Object anchors are not allowed", Line: 3, Column: 13},
+		},
+		{
+			name:   "scalar anchor",
+			source: "levels:\n  \"106391128718245888\": &admin 100\n  \"108552944961454080\": *admin\n",
+		},
+		{
+			name:   "nested within the limit",
+			source: strings.Repeat("[", MaxDepth) + strings.Repeat("]", MaxDepth) + "\n",
+		},
+		{
+			name:   "nested too deeply",
+			source: strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1) + "\n",
+			want:   &Error{Message: fmt.Sprintf("config is nested too deeply (more than %d levels)", MaxDepth), Line: 1, Column: MaxDepth + 1},
+		},
+		{
+			name:   "too many values",
+			source: "[" + strings.Repeat("1,", MaxNodes) + "]\n",
+			want:   &Error{Message: fmt.Sprintf("config is too large (more than %d values)", MaxNodes), Line: 1, Column: 2*(MaxNodes-2) + 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Parse() = %v, want no error", err)
+				}
+				return
+			}
+			if got, ok := err.(Error); !ok || got != *tt.want {
+				t.Errorf("Parse() = %#v, want %#v", err, *tt.want)
+			}
+		})
+	}
+}
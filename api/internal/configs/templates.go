@@ -0,0 +1,82 @@
+package configs
+
+import (
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+var templateNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// IsTemplateName reports whether name can be used for a config template, which
+// is stored under the "template-<name>" config key
+func IsTemplateName(name string) bool {
+	return templateNameRegex.MatchString(name)
+}
+
+// Extends returns the template a parsed guild config builds on, or "" if it doesn't
+func Extends(root *yaml.Node) string {
+	if root == nil || root.Kind != yaml.MappingNode {
+		return ""
+	}
+	if _, value := mappingEntry(root, "extends"); value != nil && isString(value) {
+		return value.Value
+	}
+	return ""
+}
+
+// MergeTemplate lays a parsed guild config over the template it extends, the way
+// the bot does when it loads the config: mappings are merged key by key, while
+// lists and plain values from the guild config replace the template's. The extends
+// key itself is dropped. Neither input is modified, and either may be nil.
+//
+// Keep in sync with applyConfigTemplate in backend/src/configTemplates.ts
+func MergeTemplate(template, guild *yaml.Node) *yaml.Node {
+	template, guild = withoutExtends(template), withoutExtends(guild)
+	switch {
+	case template == nil && guild == nil:
+		return nil
+	case template == nil:
+		return guild
+	case guild == nil:
+		return template
+	}
+	return mergeNodes(template, guild)
+}
+
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return copyNode(overlay)
+	}
+
+	out := copyNode(base)
+	forEachPair(overlay, func(key, value *yaml.Node) {
+		for i := 0; i+1 < len(out.Content); i += 2 {
+			if out.Content[i].Value == key.Value {
+				out.Content[i+1] = mergeNodes(out.Content[i+1], value)
+				return
+			}
+		}
+		out.Content = append(out.Content, copyNode(key), copyNode(value))
+	})
+	return out
+}
+
+// withoutExtends returns a copy of a config's top level without its extends key
+func withoutExtends(root *yaml.Node) *yaml.Node {
+	if root == nil || isNull(root) {
+		return nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return root
+	}
+
+	out := *root
+	out.Content = make([]*yaml.Node, 0, len(root.Content))
+	forEachPair(root, func(key, value *yaml.Node) {
+		if key.Value != "extends" {
+			out.Content = append(out.Content, key, value)
+		}
+	})
+	return &out
+}
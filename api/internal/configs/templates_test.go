@@ -0,0 +1,166 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+)
+
+// These follow mergeConfigs in backend/src/configTemplates.ts, which the bot uses
+// to load a guild config that extends a template
+func TestMergeTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		guild    string
+		want     any
+	}{
+		{
+			name:     "maps merge",
+			template: "prefix: \"!\"\nplugins:\n  utility:\n    config:\n      can_ping: true\n      can_roles: true\n  tags: {}\n",
+			guild:    "extends: base\nplugins:\n  utility:\n    config:\n      can_ping: false\n  automod: {}\n",
+			want: map[string]any{
+				"prefix": "!",
+				"plugins": map[string]any{
+					"utility": map[string]any{"config": map[string]any{"can_ping": false, "can_roles": true}},
+					"tags":    map[string]any{},
+					"automod": map[string]any{},
+				},
+			},
+		},
+		{
+			name:     "lists replace",
+			template: "plugins:\n  automod:\n    overrides:\n      - level: \">=50\"\n        config: {}\n      - level: \">=100\"\n        config: {}\n",
+			guild:    "plugins:\n  automod:\n    overrides:\n      - level: \">=75\"\n        config: {}\n",
+			want: map[string]any{
+				"plugins": map[string]any{
+					"automod": map[string]any{"overrides": []any{map[string]any{"level": ">=75", "config": map[string]any{}}}},
+				},
+			},
+		},
+		{
+			name:     "values replace",
+			template: "prefix: \"!\"\nlevels:\n  \"106391128718245888\": 100\n",
+			guild:    "prefix: \"?\"\nlevels:\n  \"106391128718245888\": 50\n  \"108552944961454080\": 100\n",
+			want: map[string]any{
+				"prefix": "?",
+				"levels": map[string]any{"106391128718245888": 50, "108552944961454080": 100},
+			},
+		},
+		{
+			name:     "a map replaces a value and the other way round",
+			template: "a: 1\nb:\n  c: 1\n",
+			guild:    "a:\n  c: 1\nb: 2\n",
+			want:     map[string]any{"a": map[string]any{"c": 1}, "b": 2},
+		},
+		{
+			name:     "null replaces",
+			template: "plugins:\n  tags:\n    config: {}\n",
+			guild:    "plugins:\n  tags: null\n",
+			want:     map[string]any{"plugins": map[string]any{"tags": nil}},
+		},
+		{
+			name:     "extends is dropped from both",
+			template: "extends: other\nprefix: \"!\"\n",
+			guild:    "extends: base\n",
+			want:     map[string]any{"prefix": "!"},
+		},
+		{
+			name:     "empty guild config",
+			template: "prefix: \"!\"\n",
+			guild:    "",
+			want:     map[string]any{"prefix": "!"},
+		},
+		{
+			name:     "empty template",
+			template: "",
+			guild:    "extends: base\nprefix: \"!\"\n",
+			want:     map[string]any{"prefix": "!"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := Parse(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			guild, err := Parse(tt.guild)
+			if err != nil {
+				t.Fatal(err)
+			}
+			templateBefore, guildBefore := tt.template, tt.guild
+			if template != nil {
+				templateBefore, _ = Encode(template, 2)
+			}
+			if guild != nil {
+				guildBefore, _ = Encode(guild, 2)
+			}
+
+			merged := MergeTemplate(template, guild)
+			if merged == nil {
+				t.Fatal("MergeTemplate() = nil")
+			}
+			if got := decode(merged); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeTemplate() = %#v, want %#v", got, tt.want)
+			}
+
+			// Neither input is touched
+			if template != nil {
+				if after, _ := Encode(template, 2); after != templateBefore {
+					t.Errorf("the template changed to\n%s", after)
+				}
+			}
+			if guild != nil {
+				if after, _ := Encode(guild, 2); after != guildBefore {
+					t.Errorf("the guild config changed to\n%s", after)
+				}
+			}
+		})
+	}
+
+	if got := MergeTemplate(nil, nil); got != nil {
+		t.Errorf("MergeTemplate(nil, nil) = %#v, want nil", got)
+	}
+}
+
+func TestExtends(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", ""},
+		{"prefix: \"!\"\n", ""},
+		{"extends: base\n", "base"},
+		{"extends: [base]\n", ""},
+	}
+	for _, tt := range tests {
+		root, err := Parse(tt.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Extends(root); got != tt.want {
+			t.Errorf("Extends(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestIsTemplateName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"base", true},
+		{"community-2", true},
+		{"big_servers", true},
+		{"", false},
+		{"-base", false},
+		{"Base", false},
+		{"base/other", false},
+		{"a23456789012345678901234567890123", false},
+	}
+	for _, tt := range tests {
+		if got := IsTemplateName(tt.name); got != tt.want {
+			t.Errorf("IsTemplateName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
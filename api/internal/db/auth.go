@@ -9,12 +9,15 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/owdiscord/athena/api/internal/models"
 )
 
-func (db *DB) CreateAPIKey(ctx context.Context, userID string) (string, error) {
+// CreateAPIKey starts a new login for the user. The user agent and IP prefix are
+// kept so the user can tell their sessions apart later.
+func (db *DB) CreateAPIKey(ctx context.Context, userID, userAgent, ipPrefix string) (string, error) {
 	// Generate unique loginID (UUIDv7)
 	loginUUID, err := uuid.NewV7()
 	if err != nil {
@@ -36,8 +39,8 @@ func (db *DB) CreateAPIKey(ctx context.Context, userID string) (string, error) {
 	hashedToken := hex.EncodeToString(hash.Sum(nil))
 
 	_, err = db.conn.ExecContext(ctx,
-		"INSERT INTO api_logins (id, token, user_id, logged_in_at, expires_at) VALUES (?, ?, ?, now(), DATE_ADD(now(), INTERVAL 24 HOUR))",
-		loginID, hashedToken, userID,
+		"INSERT INTO api_logins (id, token, user_id, logged_in_at, last_used_at, expires_at, user_agent, ip_prefix) VALUES (?, ?, ?, now(), now(), DATE_ADD(now(), INTERVAL 24 HOUR), NULLIF(?, ''), NULLIF(?, ''))",
+		loginID, hashedToken, userID, truncate(userAgent, 255), ipPrefix,
 	)
 	if err != nil {
 		return "", fmt.Errorf("failed to save api key: %w", err)
@@ -78,16 +81,51 @@ func (db *DB) ExpireAPIKey(ctx context.Context, apiKey string) error {
 	return err
 }
 
+// RefreshAPIKeyExpiry pushes a login's expiry back a day. Logins that have already
+// expired are left alone, so a refresh racing a revocation can't bring it back.
 func (db *DB) RefreshAPIKeyExpiry(ctx context.Context, apiKey string) error {
 	loginID, _, err := extractToken(apiKey)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.conn.ExecContext(ctx, "UPDATE api_logins SET expires_at = DATE_ADD(now(), INTERVAL 24 HOUR) WHERE id = ?", loginID)
+	_, err = db.conn.ExecContext(ctx, "UPDATE api_logins SET expires_at = DATE_ADD(now(), INTERVAL 24 HOUR), last_used_at = now() WHERE id = ? AND expires_at > now()", loginID)
 	return err
 }
 
+// GetSessions lists the user's unexpired logins, most recently used first
+func (db *DB) GetSessions(ctx context.Context, userID string) ([]models.APISession, error) {
+	sessions := []models.APISession{}
+	err := db.conn.SelectContext(ctx, &sessions, `
+		SELECT id, logged_in_at, last_used_at, expires_at, user_agent, ip_prefix
+		FROM api_logins
+		WHERE user_id = ? AND expires_at > now()
+		ORDER BY COALESCE(last_used_at, logged_in_at) DESC
+	`, userID)
+	return sessions, err
+}
+
+// RevokeSession expires one of the user's logins, reporting false if they have no
+// unexpired login with that ID
+func (db *DB) RevokeSession(ctx context.Context, userID, loginID string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, "UPDATE api_logins SET expires_at = now() WHERE id = ? AND user_id = ? AND expires_at > now()", loginID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeOtherSessions expires all of the user's logins except exceptLoginID,
+// returning how many were revoked. An empty exceptLoginID revokes them all.
+func (db *DB) RevokeOtherSessions(ctx context.Context, userID, exceptLoginID string) (int64, error) {
+	res, err := db.conn.ExecContext(ctx, "UPDATE api_logins SET expires_at = now() WHERE user_id = ? AND id != ? AND expires_at > now()", userID, exceptLoginID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (db *DB) UpsertUserInfo(ctx context.Context, userID, username, avatar string) error {
 	// Matches TypeORM simple-json format exactly
 	data, err := json.Marshal(map[string]string{
@@ -106,6 +144,15 @@ func (db *DB) UpsertUserInfo(ctx context.Context, userID, username, avatar strin
 	return err
 }
 
+// LoginID returns the login an API key belongs to, or "" if the key is malformed
+func LoginID(apiKey string) string {
+	loginID, _, err := extractToken(apiKey)
+	if err != nil {
+		return ""
+	}
+	return loginID
+}
+
 func extractToken(input string) (string, string, error) {
 	split := strings.Split(input, ".")
 	if len(split) < 2 {
@@ -114,3 +161,14 @@ func extractToken(input string) (string, string, error) {
 
 	return split[0], split[1], nil
 }
+
+// truncate cuts s down to at most n bytes, without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
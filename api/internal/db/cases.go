@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/owdiscord/athena/api/internal/models"
+)
+
+const caseColumns = "id, guild_id, case_number, user_id, user_name, mod_id, mod_name, type, created_at, is_hidden, pp_id, pp_name, log_message_id"
+
+// CaseFilter narrows down the cases returned by GetCases. Zero values are ignored.
+type CaseFilter struct {
+	UserID string
+	ModID  string
+	Type   *models.CaseType
+	Hidden *bool
+	From   *time.Time
+	To     *time.Time
+
+	// Before is the pagination cursor: only cases with a lower case number are returned
+	Before int
+	Limit  int
+}
+
+// GetCases returns the guild's cases matching the filter, newest case number first
+func (db *DB) GetCases(ctx context.Context, guildID string, filter CaseFilter) ([]models.Case, error) {
+	where := []string{"guild_id = ?"}
+	args := []any{guildID}
+
+	if filter.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.ModID != "" {
+		where = append(where, "mod_id = ?")
+		args = append(args, filter.ModID)
+	}
+	if filter.Type != nil {
+		where = append(where, "type = ?")
+		args = append(args, *filter.Type)
+	}
+	if filter.Hidden != nil {
+		where = append(where, "is_hidden = ?")
+		args = append(args, *filter.Hidden)
+	}
+	if filter.From != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, *filter.To)
+	}
+	if filter.Before > 0 {
+		where = append(where, "case_number < ?")
+		args = append(args, filter.Before)
+	}
+	args = append(args, filter.Limit)
+
+	cases := []models.Case{}
+	err := db.conn.SelectContext(ctx, &cases,
+		"SELECT "+caseColumns+" FROM cases WHERE "+strings.Join(where, " AND ")+" ORDER BY case_number DESC LIMIT ?",
+		args...,
+	)
+	return cases, err
+}
+
+func (db *DB) GetCaseByNumber(ctx context.Context, guildID string, caseNumber int) (*models.Case, error) {
+	var c models.Case
+	err := db.conn.GetContext(ctx, &c, "SELECT "+caseColumns+" FROM cases WHERE guild_id = ? AND case_number = ?", guildID, caseNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &c, err
+}
+
+func (db *DB) GetCaseNotes(ctx context.Context, caseID int64) ([]models.CaseNote, error) {
+	notes := []models.CaseNote{}
+	err := db.conn.SelectContext(ctx, &notes, `
+		SELECT id, case_id, mod_id, mod_name, body, created_at FROM case_notes WHERE case_id = ? ORDER BY id ASC
+	`, caseID)
+	return notes, err
+}
+
+// ExportCases walks every case in the guild in case number order, with notes
+// attached, calling fn once per case. Rows are read straight off the cursor so
+// only a single case is held in memory at a time.
+func (db *DB) ExportCases(ctx context.Context, guildID string, fn func(*models.Case) error) error {
+	rows, err := db.conn.QueryxContext(ctx, `
+		SELECT c.id, c.guild_id, c.case_number, c.user_id, c.user_name, c.mod_id, c.mod_name, c.type,
+			c.created_at, c.is_hidden, c.pp_id, c.pp_name, c.log_message_id,
+			n.id AS note_id, n.mod_id AS note_mod_id, n.mod_name AS note_mod_name,
+			n.body AS note_body, n.created_at AS note_created_at
+		FROM cases c
+		LEFT JOIN case_notes n ON n.case_id = c.id
+		WHERE c.guild_id = ?
+		ORDER BY c.case_number ASC, n.id ASC
+	`, guildID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current *models.Case
+	for rows.Next() {
+		var row struct {
+			models.Case
+			NoteID        *int64     `db:"note_id"`
+			NoteModID     *string    `db:"note_mod_id"`
+			NoteModName   *string    `db:"note_mod_name"`
+			NoteBody      *string    `db:"note_body"`
+			NoteCreatedAt *time.Time `db:"note_created_at"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
+
+		if current == nil || current.ID != row.ID {
+			if current != nil {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+			c := row.Case
+			c.Notes = []models.CaseNote{}
+			current = &c
+		}
+
+		if row.NoteID != nil {
+			current.Notes = append(current.Notes, models.CaseNote{
+				ID:        *row.NoteID,
+				CaseID:    row.ID,
+				ModID:     row.NoteModID,
+				ModName:   row.NoteModName,
+				Body:      *row.NoteBody,
+				CreatedAt: *row.NoteCreatedAt,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if current != nil {
+		return fn(current)
+	}
+	return nil
+}
+
+// GetCaseNumberRange returns the lowest and highest case numbers in the guild, or zeroes if it has no cases
+func (db *DB) GetCaseNumberRange(ctx context.Context, guildID string) (int, int, error) {
+	var row struct {
+		Min int `db:"min_case_number"`
+		Max int `db:"max_case_number"`
+	}
+	err := db.conn.GetContext(ctx, &row, `
+		SELECT COALESCE(MIN(case_number), 0) AS min_case_number, COALESCE(MAX(case_number), 0) AS max_case_number
+		FROM cases WHERE guild_id = ?
+	`, guildID)
+	return row.Min, row.Max, err
+}
+
+// GetCaseNumbers returns every case number in use in the guild, in ascending order
+func (db *DB) GetCaseNumbers(ctx context.Context, guildID string) ([]int, error) {
+	numbers := []int{}
+	err := db.conn.SelectContext(ctx, &numbers, "SELECT case_number FROM cases WHERE guild_id = ? ORDER BY case_number ASC", guildID)
+	return numbers, err
+}
+
+// LockCaseNumbers is GetCaseNumbers for an import that's about to go ahead. The rows
+// are locked until the transaction ends, so the bot can't create a case that takes
+// one of the numbers the import is about to use.
+func (db *DB) LockCaseNumbers(tx *sqlx.Tx, ctx context.Context, guildID string) ([]int, error) {
+	numbers := []int{}
+	err := tx.SelectContext(ctx, &numbers, "SELECT case_number FROM cases WHERE guild_id = ? ORDER BY case_number ASC FOR UPDATE", guildID)
+	return numbers, err
+}
+
+// DeleteAllCases removes every case in the guild. Notes go with them through the case_notes foreign key.
+func (db *DB) DeleteAllCases(tx *sqlx.Tx, ctx context.Context, guildID string) (int64, error) {
+	res, err := tx.ExecContext(ctx, "DELETE FROM cases WHERE guild_id = ?", guildID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// BumpCaseNumbers shifts every case number in the guild up by amount
+func (db *DB) BumpCaseNumbers(tx *sqlx.Tx, ctx context.Context, guildID string, amount int) (int64, error) {
+	// Working from the top down keeps the (guild_id, case_number) unique index happy mid-update
+	res, err := tx.ExecContext(ctx, "UPDATE cases SET case_number = case_number + ? WHERE guild_id = ? ORDER BY case_number DESC", amount, guildID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// InsertCase creates the case along with its notes, returning the new case ID
+func (db *DB) InsertCase(tx *sqlx.Tx, ctx context.Context, c *models.Case) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO cases (guild_id, case_number, user_id, user_name, mod_id, mod_name, type, created_at, is_hidden, pp_id, pp_name, log_message_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.GuildID, c.CaseNumber, c.UserID, c.UserName, c.ModID, c.ModName, c.Type, c.CreatedAt, c.IsHidden, c.PPID, c.PPName, c.LogMessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	caseID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, note := range c.Notes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO case_notes (case_id, mod_id, mod_name, body, created_at) VALUES (?, ?, ?, ?, ?)
+		`, caseID, note.ModID, note.ModName, note.Body, note.CreatedAt)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return caseID, nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/owdiscord/athena/api/internal/models"
+)
+
+const revisionColumns = "c.id, c.`key`, c.edited_by, c.edited_at, c.is_active, JSON_UNQUOTE(JSON_EXTRACT(u.data, '$.username')) AS edited_by_name"
+
+// GetConfigRevisions lists the revisions of a config newest first, without their bodies.
+// before is the pagination cursor: only revisions with a lower ID are returned.
+func (db *DB) GetConfigRevisions(ctx context.Context, key string, before int64, limit int) ([]models.ConfigRevision, error) {
+	query := "SELECT " + revisionColumns + " FROM configs c LEFT JOIN api_user_info u ON u.id = c.edited_by WHERE c.`key` = ?"
+	args := []any{key}
+	if before > 0 {
+		query += " AND c.id < ?"
+		args = append(args, before)
+	}
+	query += " ORDER BY c.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	revisions := []models.ConfigRevision{}
+	err := db.conn.SelectContext(ctx, &revisions, query, args...)
+	return revisions, err
+}
+
+// GetConfigRevision returns a single revision of the config with its body, or nil
+// if there's no revision with that ID under the key
+func (db *DB) GetConfigRevision(ctx context.Context, key string, id int64) (*models.ConfigRevision, error) {
+	var revision models.ConfigRevision
+	err := db.conn.GetContext(ctx, &revision,
+		"SELECT "+revisionColumns+", c.config FROM configs c LEFT JOIN api_user_info u ON u.id = c.edited_by WHERE c.`key` = ? AND c.id = ?",
+		key, id,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &revision, err
+}
+
+// LockActiveConfigID returns the ID of the active revision under key, or 0 if there
+// isn't one, locking it until the transaction ends so nobody can publish over it
+func (db *DB) LockActiveConfigID(tx *sqlx.Tx, ctx context.Context, key string) (int64, error) {
+	var id int64
+	err := tx.GetContext(ctx, &id, "SELECT id FROM configs WHERE `key` = ? AND is_active = true ORDER BY edited_at DESC LIMIT 1 FOR UPDATE", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// GetActiveConfigsByPrefix lists the active revision of every config whose key starts
+// with prefix, without their bodies
+func (db *DB) GetActiveConfigsByPrefix(ctx context.Context, prefix string) ([]models.ConfigRevision, error) {
+	revisions := []models.ConfigRevision{}
+	err := db.conn.SelectContext(ctx, &revisions,
+		"SELECT "+revisionColumns+" FROM configs c LEFT JOIN api_user_info u ON u.id = c.edited_by WHERE c.`key` LIKE ? AND c.is_active = true ORDER BY c.`key` ASC",
+		prefix+"%",
+	)
+	return revisions, err
+}
+
+// GetActiveGuildConfigsContaining returns the active guild configs whose body
+// contains text anywhere, as a cheap first pass before parsing them
+func (db *DB) GetActiveGuildConfigsContaining(ctx context.Context, text string) ([]models.Config, error) {
+	configs := []models.Config{}
+	err := db.conn.SelectContext(ctx, &configs,
+		"SELECT id, `key`, config, edited_by, edited_at FROM configs WHERE `key` LIKE 'guild-%' AND is_active = true AND LOCATE(?, config) > 0",
+		text,
+	)
+	return configs, err
+}
+
+// DeactivateConfig retires every revision under key, so the key has no active
+// config at all. It reports false if there was no active config to begin with.
+func (db *DB) DeactivateConfig(ctx context.Context, key string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, "UPDATE configs SET is_active = false WHERE `key` = ? AND is_active = true", key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
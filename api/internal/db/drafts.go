@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/owdiscord/athena/api/internal/models"
+)
+
+const draftColumns = "d.id, d.`key`, d.base_revision_id, d.status, d.created_by, d.created_at, d.updated_at, d.version, " +
+	"d.reviewed_by, d.reviewed_at, d.review_comment, d.published_revision_id, " +
+	"JSON_UNQUOTE(JSON_EXTRACT(u.data, '$.username')) AS created_by_name"
+
+func (db *DB) CreateConfigDraft(ctx context.Context, key, config string, baseRevisionID *int64, userID string) (int64, error) {
+	res, err := db.conn.ExecContext(ctx,
+		"INSERT INTO config_drafts (`key`, config, base_revision_id, status, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, NOW(), NOW())",
+		key, config, baseRevisionID, models.DraftPending, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateConfigDraft replaces the body of a pending draft written by userID, bumping
+// its version. It reports false if they have no pending draft with that ID under the key.
+func (db *DB) UpdateConfigDraft(ctx context.Context, key string, id int64, userID, config string, baseRevisionID *int64) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		"UPDATE config_drafts SET config = ?, base_revision_id = ?, updated_at = NOW(), version = version + 1 WHERE id = ? AND `key` = ? AND created_by = ? AND status = ?",
+		config, baseRevisionID, id, key, userID, models.DraftPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetConfigDrafts lists the drafts under key with the given status newest first, without their bodies
+func (db *DB) GetConfigDrafts(ctx context.Context, key, status string) ([]models.ConfigDraft, error) {
+	drafts := []models.ConfigDraft{}
+	err := db.conn.SelectContext(ctx, &drafts,
+		"SELECT "+draftColumns+" FROM config_drafts d LEFT JOIN api_user_info u ON u.id = d.created_by WHERE d.`key` = ? AND d.status = ? ORDER BY d.id DESC",
+		key, status,
+	)
+	return drafts, err
+}
+
+// GetConfigDraft returns a single draft with its body, or nil if there's no draft
+// with that ID under the key
+func (db *DB) GetConfigDraft(ctx context.Context, key string, id int64) (*models.ConfigDraft, error) {
+	var draft models.ConfigDraft
+	err := db.conn.GetContext(ctx, &draft,
+		"SELECT "+draftColumns+", d.config FROM config_drafts d LEFT JOIN api_user_info u ON u.id = d.created_by WHERE d.`key` = ? AND d.id = ?",
+		key, id,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &draft, err
+}
+
+// ApproveConfigDraft marks a pending draft as approved and published as revisionID,
+// reporting false if it had already been reviewed or is no longer at version
+func (db *DB) ApproveConfigDraft(tx *sqlx.Tx, ctx context.Context, id int64, version int, reviewerID string, revisionID int64) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		"UPDATE config_drafts SET status = ?, reviewed_by = ?, reviewed_at = NOW(), published_revision_id = ? WHERE id = ? AND version = ? AND status = ?",
+		models.DraftApproved, reviewerID, revisionID, id, version, models.DraftPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RejectConfigDraft marks a pending draft as rejected, reporting false if there's
+// no pending draft with that ID under the key
+func (db *DB) RejectConfigDraft(ctx context.Context, key string, id int64, reviewerID, comment string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		"UPDATE config_drafts SET status = ?, reviewed_by = ?, reviewed_at = NOW(), review_comment = ? WHERE id = ? AND `key` = ? AND status = ?",
+		models.DraftRejected, reviewerID, comment, id, key, models.DraftPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -131,25 +131,77 @@ func (db *DB) UpdateUserPermissions(ctx context.Context, guildID, userID string,
 	return err
 }
 
+// RemoveUserPermissions takes away the user's permissions in the guild. If those
+// were the last they had anywhere, their logins are revoked as well.
 func (db *DB) RemoveUserPermissions(ctx context.Context, guildID, userID string) error {
-	_, err := db.conn.ExecContext(ctx, `
+	tx, err := db.Tx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
 		DELETE FROM api_permissions
 		WHERE guild_id = ? AND type = 'USER' AND target_id = ?
-	`, guildID, userID)
-	return err
+	`, guildID, userID); err != nil {
+		return err
+	}
+	if err := revokeLoginsWithoutPermissions(tx, ctx, []string{userID}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// ClearExpiredPermissions removes permissions past their expiry, revoking the logins
+// of anyone left without any. The bot deletes expired permissions on its own timer
+// as well, often before we get to them, so rather than only the users whose rows
+// were deleted here, everyone with a live login and no permissions left is logged out.
 func (db *DB) ClearExpiredPermissions(ctx context.Context) error {
-	_, err := db.conn.ExecContext(ctx, `
+	tx, err := db.Tx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
 		DELETE FROM api_permissions
 		WHERE expires_at IS NOT NULL AND expires_at <= NOW()
-	`)
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE api_logins SET expires_at = NOW()
+		WHERE expires_at > NOW()
+		AND NOT EXISTS (SELECT 1 FROM api_permissions p WHERE p.type = 'USER' AND p.target_id = api_logins.user_id)
+	`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// revokeLoginsWithoutPermissions expires the logins of those users who no longer
+// have permissions in any guild, so they can't carry on using the dashboard until
+// their key runs out
+func revokeLoginsWithoutPermissions(tx *sqlx.Tx, ctx context.Context, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`
+		UPDATE api_logins SET expires_at = NOW()
+		WHERE expires_at > NOW() AND user_id IN (?)
+		AND NOT EXISTS (SELECT 1 FROM api_permissions p WHERE p.type = 'USER' AND p.target_id = api_logins.user_id)
+	`, userIDs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), args...)
 	return err
 }
 
 func (db *DB) GetActiveConfig(ctx context.Context, key string) (*models.Config, error) {
 	var config models.Config
-	err := db.conn.GetContext(ctx, &config, "SELECT `key`, config, edited_by, edited_at FROM configs WHERE `key` = ? AND is_active = true ORDER BY edited_at DESC LIMIT 1", key)
+	err := db.conn.GetContext(ctx, &config, "SELECT id, `key`, config, edited_by, edited_at FROM configs WHERE `key` = ? AND is_active = true ORDER BY edited_at DESC LIMIT 1", key)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -161,9 +213,12 @@ func (db *DB) MarkOldConfigsInactive(tx *sqlx.Tx, ctx context.Context, key strin
 	return err
 }
 
-func (db *DB) SaveConfigRevision(tx *sqlx.Tx, ctx context.Context, key, config, userID string) error {
-	_, err := tx.ExecContext(ctx, "INSERT INTO configs (`key`, config, edited_by, edited_at, is_active) VALUES (?, ?, ?, NOW(), true)", key, config, userID)
-	return err
+func (db *DB) SaveConfigRevision(tx *sqlx.Tx, ctx context.Context, key, config, userID string) (int64, error) {
+	res, err := tx.ExecContext(ctx, "INSERT INTO configs (`key`, config, edited_by, edited_at, is_active) VALUES (?, ?, ?, NOW(), true)", key, config, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
 }
 
 func (db *DB) AddAuditLog(ctx context.Context, guildID, userID, eventType string, data map[string]any) error {
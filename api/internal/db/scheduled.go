@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/owdiscord/athena/api/internal/models"
+)
+
+const scheduledColumns = "s.id, s.`key`, s.publish_at, s.revert_at, s.status, s.created_by, s.created_at, " +
+	"s.previous_revision_id, s.published_revision_id, s.reverted_revision_id, " +
+	"JSON_UNQUOTE(JSON_EXTRACT(u.data, '$.username')) AS created_by_name"
+
+func (db *DB) CreateScheduledConfig(ctx context.Context, key, config string, publishAt time.Time, revertAt *time.Time, userID string) (int64, error) {
+	res, err := db.conn.ExecContext(ctx,
+		"INSERT INTO scheduled_configs (`key`, config, publish_at, revert_at, status, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, NOW())",
+		key, config, publishAt, revertAt, models.ScheduleScheduled, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetScheduledConfigs lists everything scheduled under key, latest publish time
+// first, without their bodies. An empty status lists all of them.
+func (db *DB) GetScheduledConfigs(ctx context.Context, key, status string) ([]models.ScheduledConfig, error) {
+	query := "SELECT " + scheduledColumns + " FROM scheduled_configs s LEFT JOIN api_user_info u ON u.id = s.created_by WHERE s.`key` = ?"
+	args := []any{key}
+	if status != "" {
+		query += " AND s.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY s.publish_at DESC, s.id DESC"
+
+	scheduled := []models.ScheduledConfig{}
+	err := db.conn.SelectContext(ctx, &scheduled, query, args...)
+	return scheduled, err
+}
+
+// GetScheduledConfig returns a single scheduled config with its body, or nil if
+// there's none with that ID under the key
+func (db *DB) GetScheduledConfig(ctx context.Context, key string, id int64) (*models.ScheduledConfig, error) {
+	var scheduled models.ScheduledConfig
+	err := db.conn.GetContext(ctx, &scheduled,
+		"SELECT "+scheduledColumns+", s.config FROM scheduled_configs s LEFT JOIN api_user_info u ON u.id = s.created_by WHERE s.`key` = ? AND s.id = ?",
+		key, id,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &scheduled, err
+}
+
+// CancelScheduledConfig calls off a config that hasn't been published yet,
+// reporting false if there's no such config waiting under the key
+func (db *DB) CancelScheduledConfig(ctx context.Context, key string, id int64) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		"UPDATE scheduled_configs SET status = ? WHERE id = ? AND `key` = ? AND status = ?",
+		models.ScheduleCancelled, id, key, models.ScheduleScheduled,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetDueScheduledConfigs returns the scheduled configs, with bodies, whose publish time has passed
+func (db *DB) GetDueScheduledConfigs(ctx context.Context) ([]models.ScheduledConfig, error) {
+	scheduled := []models.ScheduledConfig{}
+	err := db.conn.SelectContext(ctx, &scheduled,
+		"SELECT "+scheduledColumns+", s.config FROM scheduled_configs s LEFT JOIN api_user_info u ON u.id = s.created_by WHERE s.status = ? AND s.publish_at <= NOW() ORDER BY s.publish_at ASC, s.id ASC",
+		models.ScheduleScheduled,
+	)
+	return scheduled, err
+}
+
+// GetDueScheduledReverts returns the published scheduled configs whose revert time has passed
+func (db *DB) GetDueScheduledReverts(ctx context.Context) ([]models.ScheduledConfig, error) {
+	scheduled := []models.ScheduledConfig{}
+	err := db.conn.SelectContext(ctx, &scheduled,
+		"SELECT "+scheduledColumns+" FROM scheduled_configs s LEFT JOIN api_user_info u ON u.id = s.created_by WHERE s.status = ? AND s.revert_at IS NOT NULL AND s.revert_at <= NOW() ORDER BY s.revert_at ASC, s.id ASC",
+		models.SchedulePublished,
+	)
+	return scheduled, err
+}
+
+// MarkScheduledConfigPublished records that a scheduled config went live as revisionID,
+// replacing previousID. It reports false if the config was no longer waiting to go out.
+func (db *DB) MarkScheduledConfigPublished(tx *sqlx.Tx, ctx context.Context, id int64, previousID *int64, revisionID int64) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		"UPDATE scheduled_configs SET status = ?, previous_revision_id = ?, published_revision_id = ? WHERE id = ? AND status = ?",
+		models.SchedulePublished, previousID, revisionID, id, models.ScheduleScheduled,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// MarkScheduledConfigReverted records that a published scheduled config was swapped
+// back out for revisionID, reporting false if it had already been reverted
+func (db *DB) MarkScheduledConfigReverted(tx *sqlx.Tx, ctx context.Context, id int64, revisionID int64) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		"UPDATE scheduled_configs SET status = ?, reverted_revision_id = ? WHERE id = ? AND status = ?",
+		models.ScheduleReverted, revisionID, id, models.SchedulePublished,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// MarkScheduledConfigFailed gives up on a scheduled config that's still in the given status
+func (db *DB) MarkScheduledConfigFailed(ctx context.Context, id int64, status string) error {
+	_, err := db.conn.ExecContext(ctx, "UPDATE scheduled_configs SET status = ? WHERE id = ? AND status = ?", models.ScheduleFailed, id, status)
+	return err
+}
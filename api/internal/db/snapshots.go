@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/owdiscord/athena/api/internal/models"
+)
+
+// GetGuildSnapshot returns the roles and channels the bot last saw in the guild,
+// or nil if it hasn't saved any yet
+func (db *DB) GetGuildSnapshot(ctx context.Context, guildID string) (*models.GuildSnapshot, error) {
+	var row struct {
+		GuildID    string    `db:"guild_id"`
+		RoleIDs    string    `db:"role_ids"`    // JSON array
+		ChannelIDs string    `db:"channel_ids"` // JSON array
+		UpdatedAt  time.Time `db:"updated_at"`
+	}
+	err := db.conn.GetContext(ctx, &row, "SELECT guild_id, role_ids, channel_ids, updated_at FROM guild_snapshots WHERE guild_id = ?", guildID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.GuildSnapshot{GuildID: row.GuildID, UpdatedAt: row.UpdatedAt}
+	if err := json.Unmarshal([]byte(row.RoleIDs), &snapshot.RoleIDs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(row.ChannelIDs), &snapshot.ChannelIDs); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetCachedMemberIDs returns which of the given user IDs are in the guild's member cache
+func (db *DB) GetCachedMemberIDs(ctx context.Context, guildID string, userIDs []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return found, nil
+	}
+
+	query, args, err := sqlx.In("SELECT user_id FROM member_cache WHERE guild_id = ? AND user_id IN (?)", guildID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if err := db.conn.SelectContext(ctx, &ids, db.conn.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		found[id] = true
+	}
+	return found, nil
+}
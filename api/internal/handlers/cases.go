@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/db"
+	"github.com/owdiscord/athena/api/internal/models"
+	"github.com/owdiscord/athena/api/internal/permissions"
+)
+
+const (
+	defaultCasePageSize = 50
+	maxCasePageSize     = 200
+)
+
+func (h *Handler) ListCases(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ViewCases) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	filter := db.CaseFilter{Limit: defaultCasePageSize}
+
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		filter.Limit = min(limit, maxCasePageSize)
+	}
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := strconv.Atoi(v)
+		if err != nil || cursor < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+		}
+		filter.Before = cursor
+	}
+	if v := c.QueryParam("userId"); v != "" {
+		if !isSnowflake(v) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid userId")
+		}
+		filter.UserID = v
+	}
+	if v := c.QueryParam("modId"); v != "" {
+		if !isSnowflake(v) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid modId")
+		}
+		filter.ModID = v
+	}
+	if v := c.QueryParam("type"); v != "" {
+		caseType, ok := parseCaseType(v)
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid type")
+		}
+		filter.Type = &caseType
+	}
+	if v := c.QueryParam("hidden"); v != "" {
+		hidden, err := strconv.ParseBool(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid hidden")
+		}
+		filter.Hidden = &hidden
+	}
+	if v := c.QueryParam("from"); v != "" {
+		from, _, err := parseDateParam(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+		}
+		filter.From = &from
+	}
+	if v := c.QueryParam("to"); v != "" {
+		to, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid to")
+		}
+		// A bare date means "up to and including that day"
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	// Fetch one extra row so we know whether there's another page
+	limit := filter.Limit
+	filter.Limit++
+
+	cases, err := h.db.GetCases(c.Request().Context(), guildID, filter)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve cases", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	var nextCursor *int
+	if len(cases) > limit {
+		cases = cases[:limit]
+		nextCursor = &cases[limit-1].CaseNumber
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"cases": cases, "nextCursor": nextCursor})
+}
+
+func (h *Handler) GetCase(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ViewCases) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	caseNumber, err := strconv.Atoi(c.Param("caseNumber"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid case number")
+	}
+
+	theCase, err := h.db.GetCaseByNumber(c.Request().Context(), guildID, caseNumber)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve case", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if theCase == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	theCase.Notes, err = h.db.GetCaseNotes(c.Request().Context(), theCase.ID)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve case notes", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	return c.JSON(http.StatusOK, theCase)
+}
+
+// parseCaseType accepts either the numeric case type or its name, e.g. "3" or "note"
+func parseCaseType(s string) (models.CaseType, bool) {
+	if t, ok := models.CaseTypeNames[s]; ok {
+		return t, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < int(models.CaseTypeBan) || n > int(models.CaseTypeSoftban) {
+		return 0, false
+	}
+	return models.CaseType(n), true
+}
+
+// parseDateParam accepts an RFC 3339 timestamp or a bare YYYY-MM-DD date (in UTC),
+// and reports which of the two it was given
+func parseDateParam(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, false, err
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/models"
+	"github.com/owdiscord/athena/api/internal/permissions"
+)
+
+// errDraftReviewed is returned when someone else approved or rejected the draft first
+var errDraftReviewed = echo.NewHTTPError(http.StatusConflict, "draft has already been reviewed")
+
+// errDraftChanged is returned when the draft was edited (or reviewed) after the
+// reviewer read it
+var errDraftChanged = echo.NewHTTPError(http.StatusConflict, "draft has changed since you read it, review it again")
+
+func (h *Handler) ListConfigDrafts(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	status := c.QueryParamOr("status", models.DraftPending)
+	if status != models.DraftPending && status != models.DraftApproved && status != models.DraftRejected {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+	}
+
+	drafts, err := h.db.GetConfigDrafts(c.Request().Context(), "guild-"+guildID, status)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config drafts", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"drafts": drafts})
+}
+
+func (h *Handler) GetConfigDraft(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	draft, err := h.findConfigDraft(c, guildID, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, draft)
+}
+
+func (h *Handler) CreateConfigDraft(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.EditConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	config, base, err := bindDraft(c)
+	if err != nil {
+		return err
+	}
+
+	if status, errs := validateGuildConfig(config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	id, err := h.db.CreateConfigDraft(c.Request().Context(), "guild-"+guildID, config, base, userID)
+	if err != nil {
+		c.Logger().Error("couldn't create config draft", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "CREATE_CONFIG_DRAFT", map[string]any{
+		"draft_id":         id,
+		"base_revision_id": base,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "draft": id})
+}
+
+// UpdateConfigDraft replaces a pending draft's config. Only the draft's author may
+// edit it, otherwise a reviewer could rewrite someone else's draft and then approve
+// their own changes.
+func (h *Handler) UpdateConfigDraft(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.EditConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	draft, err := h.findConfigDraft(c, guildID, userID)
+	if err != nil {
+		return err
+	}
+	if draft.CreatedBy != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "only the draft's author can edit it")
+	}
+	id := draft.ID
+
+	config, base, err := bindDraft(c)
+	if err != nil {
+		return err
+	}
+
+	if status, errs := validateGuildConfig(config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	updated, err := h.db.UpdateConfigDraft(c.Request().Context(), "guild-"+guildID, id, userID, config, base)
+	if err != nil {
+		c.Logger().Error("couldn't update config draft", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if !updated {
+		return echo.NewHTTPError(http.StatusNotFound, "no pending draft with that id")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "EDIT_CONFIG_DRAFT", map[string]any{
+		"draft_id":         id,
+		"base_revision_id": base,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "draft": id})
+}
+
+// ApproveConfigDraft publishes a draft. The reviewer sends the version of the draft
+// they read, and only that version is published.
+func (h *Handler) ApproveConfigDraft(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ManageAccess) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var body struct {
+		Version *int `json:"version"`
+	}
+	if err := c.Bind(&body); err != nil || body.Version == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "the version of the draft being approved is required")
+	}
+
+	draft, err := h.findConfigDraft(c, guildID, userID)
+	if err != nil {
+		return err
+	}
+	if draft.Status != models.DraftPending {
+		return errDraftReviewed
+	}
+	if draft.Version != *body.Version {
+		return errDraftChanged
+	}
+	// The whole point of a draft is a second pair of eyes
+	if draft.CreatedBy == userID {
+		return echo.NewHTTPError(http.StatusForbidden, "you can't approve your own draft")
+	}
+
+	// Validation may have changed since the draft was written
+	if status, errs := validateGuildConfig(draft.Config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	key := "guild-" + guildID
+	id, err := h.publishConfig(c, key, draft.Config, draft.CreatedBy, draft.BaseRevisionID, func(tx *sqlx.Tx, revisionID int64) error {
+		approved, err := h.db.ApproveConfigDraft(tx, c.Request().Context(), draft.ID, draft.Version, userID, revisionID)
+		if err != nil {
+			c.Logger().Error("couldn't approve config draft", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		}
+		if !approved {
+			// Either reviewed or edited since we read it, and the edit is the one that
+			// matters to the reviewer
+			return errDraftChanged
+		}
+		return nil
+	})
+	if conflict, ok := err.(*configConflictError); ok {
+		return h.configConflict(c, key, *draft.BaseRevisionID, conflict.current)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "APPROVE_CONFIG_DRAFT", map[string]any{
+		"draft_id":    draft.ID,
+		"version":     draft.Version,
+		"created_by":  draft.CreatedBy,
+		"revision_id": id,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": id})
+}
+
+func (h *Handler) RejectConfigDraft(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ManageAccess) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid draft id")
+	}
+
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+	comment := strings.TrimSpace(body.Comment)
+	if comment == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "a comment is required when rejecting a draft")
+	}
+
+	rejected, err := h.db.RejectConfigDraft(c.Request().Context(), "guild-"+guildID, id, userID, comment)
+	if err != nil {
+		c.Logger().Error("couldn't reject config draft", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if !rejected {
+		return echo.NewHTTPError(http.StatusNotFound, "no pending draft with that id")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "REJECT_CONFIG_DRAFT", map[string]any{
+		"draft_id": id,
+		"comment":  comment,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok"})
+}
+
+// findConfigDraft loads the draft named in the :id route param, responding with
+// the appropriate error if it's malformed or doesn't exist
+func (h *Handler) findConfigDraft(c *echo.Context, guildID, userID string) (*models.ConfigDraft, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid draft id")
+	}
+
+	draft, err := h.db.GetConfigDraft(c.Request().Context(), "guild-"+guildID, id)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config draft", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if draft == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+	return draft, nil
+}
+
+// bindDraft reads a draft's config and the revision it was based on from the body
+func bindDraft(c *echo.Context) (string, *int64, error) {
+	var body struct {
+		Config       *string `json:"config"`
+		BaseRevision *int64  `json:"baseRevision"`
+	}
+	if err := c.Bind(&body); err != nil || body.Config == nil {
+		return "", nil, echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
+	}
+	return strings.TrimSpace(*body.Config) + "\n", body.BaseRevision, nil
+}
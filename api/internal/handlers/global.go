@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+)
+
+// globalConfigKey is where the config for the bot's global plugins lives
+const globalConfigKey = "global"
+
+func (h *Handler) GetGlobalConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	config, err := h.db.GetActiveConfig(c.Request().Context(), globalConfigKey)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve global config", "sql_error", err.Error(), "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	configStr := ""
+	var revision *int64
+	if config != nil {
+		configStr = config.Config
+		revision = &config.ID
+		c.Response().Header().Set("ETag", revisionETag(config.ID))
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"config": configStr, "revision": revision})
+}
+
+func (h *Handler) SaveGlobalConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var body struct {
+		Config       *string `json:"config"`
+		BaseRevision *int64  `json:"baseRevision"`
+	}
+	if err := c.Bind(&body); err != nil || body.Config == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
+	}
+
+	base, err := baseRevision(c, body.BaseRevision)
+	if err != nil {
+		return err
+	}
+
+	config := strings.TrimSpace(*body.Config) + "\n"
+
+	current, err := h.db.GetActiveConfig(c.Request().Context(), globalConfigKey)
+	if err == nil && current != nil && config == current.Config {
+		c.Response().Header().Set("ETag", revisionETag(current.ID))
+		return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": current.ID})
+	}
+
+	if status, errs := validateConfig(config, configs.ValidateGlobalConfig); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	id, err := h.publishConfig(c, globalConfigKey, config, userID, base, nil)
+	if conflict, ok := err.(*configConflictError); ok {
+		return h.configConflict(c, globalConfigKey, *base, conflict.current)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), noGuildID, userID, "SAVE_GLOBAL_CONFIG", map[string]any{
+		"revision_id": id,
+	})
+
+	c.Response().Header().Set("ETag", revisionETag(id))
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": id})
+}
+
+// ValidateGlobalConfig checks a candidate global config without saving it
+func (h *Handler) ValidateGlobalConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var body struct {
+		Config *string `json:"config"`
+	}
+	if err := c.Bind(&body); err != nil || body.Config == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
+	}
+
+	errs := []configs.Error{}
+	if _, found := validateConfig(*body.Config, configs.ValidateGlobalConfig); found != nil {
+		errs = found
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"valid": len(errs) == 0, "errors": errs})
+}
+
+func (h *Handler) ListGlobalConfigRevisions(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	return h.listRevisions(c, globalConfigKey, userID)
+}
+
+func (h *Handler) GetGlobalConfigRevision(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid revision id")
+	}
+
+	revision, err := h.db.GetConfigRevision(c.Request().Context(), globalConfigKey, id)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config revision", "sql_error", err.Error(), "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if revision == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	return c.JSON(http.StatusOK, revision)
+}
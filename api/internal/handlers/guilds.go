@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
 	"github.com/owdiscord/athena/api/internal/permissions"
 	"gopkg.in/yaml.v3"
 )
@@ -64,7 +68,12 @@ func (h *Handler) CheckPermission(c *echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
 	}
 
-	result := h.hasPermission(c, userID, guildID, body.Permission)
+	if !permissions.IsValid(body.Permission) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid permission")
+	}
+
+	// Asking doesn't get at anything, so staff checking their access isn't audited
+	result, _ := h.resolvePermission(c, userID, guildID, body.Permission)
 	return c.JSON(http.StatusOK, map[string]bool{"result": result})
 }
 
@@ -83,11 +92,14 @@ func (h *Handler) GetConfig(c *echo.Context) error {
 	}
 
 	configStr := ""
+	var revision *int64
 	if config != nil {
 		configStr = config.Config
+		revision = &config.ID
+		c.Response().Header().Set("ETag", revisionETag(config.ID))
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"config": configStr})
+	return c.JSON(http.StatusOK, map[string]any{"config": configStr, "revision": revision})
 }
 
 func (h *Handler) SaveConfig(c *echo.Context) error {
@@ -99,49 +111,149 @@ func (h *Handler) SaveConfig(c *echo.Context) error {
 	}
 
 	var body struct {
-		Config *string `json:"config"`
+		Config       *string `json:"config"`
+		BaseRevision *int64  `json:"baseRevision"`
 	}
 	if err := c.Bind(&body); err != nil || body.Config == nil {
 		c.Logger().Error("couldn't retrieve guild", "binding_error", err.Error(), "guildID", guildID, "userID", userID)
 		return echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
 	}
 
+	base, err := baseRevision(c, body.BaseRevision)
+	if err != nil {
+		return err
+	}
+
 	config := strings.TrimSpace(*body.Config) + "\n"
+	key := "guild-" + guildID
 
-	current, err := h.db.GetActiveConfig(c.Request().Context(), "guild-"+guildID)
+	current, err := h.db.GetActiveConfig(c.Request().Context(), key)
 	if err == nil && current != nil && config == current.Config {
-		return c.JSON(http.StatusOK, map[string]any{"result": "ok"})
+		c.Response().Header().Set("ETag", revisionETag(current.ID))
+		return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": current.ID})
+	}
+
+	return h.saveGuildConfig(c, key, config, userID, base)
+}
+
+// saveGuildConfig validates config and publishes it as the new revision under key,
+// responding with the new revision, the validation errors, or a conflict if the
+// active revision has moved on from base
+func (h *Handler) saveGuildConfig(c *echo.Context, key, config, userID string, base *int64) error {
+	if status, errs := validateGuildConfig(config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	id, err := h.publishConfig(c, key, config, userID, base, nil)
+	if conflict, ok := err.(*configConflictError); ok {
+		return h.configConflict(c, key, *base, conflict.current)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("ETag", revisionETag(id))
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": id})
+}
+
+// baseRevision works out which revision the editor started from. It can come from
+// If-Match (as handed out in GetConfig's ETag) or the body, with If-Match winning.
+// Without either it's nil, and the save goes through regardless of what's changed
+// in the meantime.
+func baseRevision(c *echo.Context, fromBody *int64) (*int64, error) {
+	if ifMatch := c.Request().Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		id, ok := parseRevisionETag(ifMatch)
+		if !ok {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid If-Match")
+		}
+		return &id, nil
+	}
+	return fromBody, nil
+}
+
+// configConflictError is returned by publishConfig when the active revision
+// isn't the one the caller based their changes on
+type configConflictError struct {
+	current int64
+}
+
+func (e *configConflictError) Error() string {
+	return fmt.Sprintf("config has moved on to revision %d", e.current)
+}
+
+// configConflict responds to a save that lost the race with someone else's, showing
+// what changed between the revision the editor started from and the live one
+func (h *Handler) configConflict(c *echo.Context, key string, base, current int64) error {
+	result := map[string]any{
+		"errors":          []string{"The config has been changed by someone else since you started editing. Reload it and apply your changes again."},
+		"currentRevision": current,
 	}
 
-	if err := validateYAML(config); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string][]string{"errors": {err.Error()}})
+	from, err := h.db.GetConfigRevision(c.Request().Context(), key, base)
+	if err == nil && from != nil {
+		to, err := h.db.GetConfigRevision(c.Request().Context(), key, current)
+		if err == nil && to != nil {
+			result["diff"] = configs.UnifiedDiff(fmt.Sprintf("revision %d", base), fmt.Sprintf("revision %d", current), from.Config, to.Config)
+		}
 	}
 
+	c.Response().Header().Set("ETag", revisionETag(current))
+	return c.JSON(http.StatusConflict, result)
+}
+
+// publishConfig saves config as a new revision under key and makes it the active one,
+// returning the new revision's ID. If base is given and the active revision isn't
+// that one, nothing is saved and a *configConflictError is returned. If then is given
+// it runs inside the same transaction once the revision is saved, and any error it
+// returns is passed back as-is after rolling back. Other failures are logged and
+// returned as HTTP errors.
+func (h *Handler) publishConfig(c *echo.Context, key, config, userID string, base *int64, then func(tx *sqlx.Tx, revisionID int64) error) (int64, error) {
 	tx, err := h.db.Tx()
 	if err != nil {
 		c.Logger().Error("cannot start transaction to save new config", "tx_err", err)
-		return c.JSON(http.StatusInternalServerError, map[string][]string{"errors": {err.Error()}})
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "server error")
 	}
 
-	if err := h.db.MarkOldConfigsInactive(tx, c.Request().Context(), "guild-"+guildID); err != nil {
+	if base != nil {
+		current, err := h.db.LockActiveConfigID(tx, c.Request().Context(), key)
+		if err != nil {
+			tx.Rollback()
+			c.Logger().Error("couldn't lock active config", "sql_error", err.Error(), "key", key, "userID", userID)
+			return 0, echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		}
+		if current != *base {
+			tx.Rollback()
+			return 0, &configConflictError{current}
+		}
+	}
+
+	if err := h.db.MarkOldConfigsInactive(tx, c.Request().Context(), key); err != nil {
 		tx.Rollback()
-		c.Logger().Error("couldn't mark old configs inactive", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
-		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		c.Logger().Error("couldn't mark old configs inactive", "sql_error", err.Error(), "key", key, "userID", userID)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "server error")
 	}
 
-	if err := h.db.SaveConfigRevision(tx, c.Request().Context(), "guild-"+guildID, config, userID); err != nil {
+	id, err := h.db.SaveConfigRevision(tx, c.Request().Context(), key, config, userID)
+	if err != nil {
 		tx.Rollback()
-		c.Logger().Error("couldn't save new config", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
-		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		c.Logger().Error("couldn't save new config", "sql_error", err.Error(), "key", key, "userID", userID)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	if then != nil {
+		if err := then(tx, id); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		tx.Rollback()
-		c.Logger().Error("couldn't commit config transaction", "tx_err", err.Error(), "guildID", guildID, "userID", userID)
-		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		c.Logger().Error("couldn't commit config transaction", "tx_err", err.Error(), "key", key, "userID", userID)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "server error")
 	}
 
-	return c.JSON(http.StatusOK, map[string]any{"result": "ok"})
+	return id, nil
 }
 
 func (h *Handler) GetPermissions(c *echo.Context) error {
@@ -232,12 +344,29 @@ func (h *Handler) SetTargetPermissions(c *echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
+// hasPermission reports whether the user holds perm in the guild. Staff have every
+// permission everywhere, but when they're using that rather than a permission of
+// their own, it's recorded as staff access.
 func (h *Handler) hasPermission(c *echo.Context, userID, guildID string, perm permissions.APIPermission) bool {
+	allowed, asStaff := h.resolvePermission(c, userID, guildID, perm)
+	if asStaff {
+		h.recordStaffAccess(c, userID, guildID, perm)
+	}
+	return allowed
+}
+
+// resolvePermission reports whether the user has perm in the guild, and whether
+// that's down to them being staff rather than anything they were granted. Staff
+// are only let in with permissions that exist, to IDs that could be a guild.
+func (h *Handler) resolvePermission(c *echo.Context, userID, guildID string, perm permissions.APIPermission) (allowed, asStaff bool) {
 	assignment, err := h.db.GetPermissionsByGuildAndUserID(c.Request().Context(), guildID, userID)
-	if err != nil || assignment == nil {
-		return false
+	if err == nil && assignment != nil && permissions.Has(assignment.Permissions, perm) {
+		return true, false
+	}
+	if isSnowflake(guildID) && permissions.IsValid(perm) && h.isStaff(userID) {
+		return true, true
 	}
-	return containsPermission(assignment.Permissions, perm) || containsPermission(assignment.Permissions, permissions.Owner)
+	return false, false
 }
 
 func containsPermission(perms []permissions.APIPermission, target permissions.APIPermission) bool {
@@ -256,7 +385,38 @@ func isSnowflake(s string) bool {
 	return true
 }
 
-func validateYAML(config string) error {
-	var out any
-	return yaml.Unmarshal([]byte(config), &out)
+func revisionETag(id int64) string {
+	return `"` + strconv.FormatInt(id, 10) + `"`
+}
+
+func parseRevisionETag(etag string) (int64, bool) {
+	etag = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	id, err := strconv.ParseInt(etag, 10, 64)
+	return id, err == nil
+}
+
+// validateGuildConfig checks a guild config before it's saved. YAML that doesn't
+// parse is a 400, while a config that parses but isn't valid is a 422, like the
+// backend gives.
+func validateGuildConfig(config string) (int, []configs.Error) {
+	return validateConfig(config, configs.ValidateGuildConfig)
+}
+
+// validateConfig is validateGuildConfig with the validation to run passed in
+func validateConfig(config string, validate func(root *yaml.Node) []configs.Error) (int, []configs.Error) {
+	root, err := configs.Parse(config)
+	if err != nil {
+		return http.StatusBadRequest, []configs.Error{err.(configs.Error)}
+	}
+	if errs := validate(root); len(errs) > 0 {
+		return http.StatusUnprocessableEntity, errs
+	}
+	return http.StatusOK, nil
+}
+
+// configErrors responds with the problems found in a config. The plain messages in
+// "errors" are what the dashboard shows, while "details" carries their paths and
+// positions in the YAML.
+func configErrors(c *echo.Context, status int, errs []configs.Error) error {
+	return c.JSON(status, map[string]any{"errors": configs.Strings(errs), "details": errs})
 }
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+)
+
+func TestValidateGuildConfigResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		status  int
+		errors  []string
+		details []configs.Error
+	}{
+		{
+			name:   "valid",
+			config: "prefix: \"!\"\nplugins:\n  utility: {}\n",
+			status: http.StatusOK,
+		},
+		{
+			name:    "invalid",
+			config:  "prefix: \"!\"\nplugins:\n  utility:\n    enabled: sometimes\n",
+			status:  http.StatusUnprocessableEntity,
+			errors:  []string{"plugins.utility.enabled: expected true or false (line 4, column 14)"},
+			details: []configs.Error{{Path: "plugins.utility.enabled", Message: "expected true or false", Line: 4, Column: 14}},
+		},
+		{
+			name:    "not yaml",
+			config:  "prefix: \"!\"\nplugins:\n  utility: [\n",
+			status:  http.StatusBadRequest,
+			errors:  []string{"did not find expected node content (line 3)"},
+			details: []configs.Error{{Message: "did not find expected node content", Line: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, errs := validateGuildConfig(tt.config)
+			if status != tt.status {
+				t.Fatalf("validateGuildConfig() status = %d, want %d", status, tt.status)
+			}
+			if errs == nil {
+				if tt.details != nil {
+					t.Fatalf("validateGuildConfig() found no errors, want %v", tt.details)
+				}
+				return
+			}
+
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+			if err := configErrors(c, status, errs); err != nil {
+				t.Fatalf("configErrors() = %v", err)
+			}
+			if rec.Code != tt.status {
+				t.Errorf("responded %d, want %d", rec.Code, tt.status)
+			}
+
+			var body struct {
+				Errors  []string        `json:"errors"`
+				Details []configs.Error `json:"details"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("bad response %s: %v", rec.Body, err)
+			}
+			if len(body.Errors) != len(tt.errors) || len(body.Details) != len(tt.details) {
+				t.Fatalf("got %+v, want errors %q and details %+v", body, tt.errors, tt.details)
+			}
+			for i := range tt.errors {
+				if body.Errors[i] != tt.errors[i] {
+					t.Errorf("errors[%d] = %q, want %q", i, body.Errors[i], tt.errors[i])
+				}
+				if body.Details[i] != tt.details[i] {
+					t.Errorf("details[%d] = %+v, want %+v", i, body.Details[i], tt.details[i])
+				}
+			}
+		})
+	}
+}
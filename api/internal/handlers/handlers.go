@@ -15,13 +15,17 @@ type Handler struct {
 	discord *discord.Config
 	db      *db.DB
 	mu      sync.Mutex
+	limits  *rateLimiter
+	staff   []string
 }
 
-func New(key string, discord *discord.Config, db *db.DB) Handler {
+func New(key string, discord *discord.Config, db *db.DB, staff []string) Handler {
 	return Handler{
 		key,
 		discord,
 		db,
 		sync.Mutex{},
+		newRateLimiter(),
+		staff,
 	}
 }
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/models"
+	"github.com/owdiscord/athena/api/internal/permissions"
+)
+
+// Matches the format moment's "YYYY-MM-DD HH:mm:ss" gives us in the backend
+const exportTimeFormat = "2006-01-02 15:04:05"
+
+const importExportRateLimit = 5 * time.Minute
+
+// exportCase is a single case in the import/export format. The field order
+// matches the backend's export so files from either API look the same.
+type exportCase struct {
+	CaseNumber   int          `json:"case_number"`
+	UserID       string       `json:"user_id"`
+	UserName     string       `json:"user_name"`
+	ModID        *string      `json:"mod_id"`
+	ModName      *string      `json:"mod_name"`
+	Type         int          `json:"type"`
+	CreatedAt    string       `json:"created_at"`
+	IsHidden     bool         `json:"is_hidden"`
+	PPID         *string      `json:"pp_id"`
+	PPName       *string      `json:"pp_name"`
+	LogMessageID *string      `json:"log_message_id,omitempty"`
+	Notes        []exportNote `json:"notes"`
+}
+
+type importData struct {
+	Cases []exportCase `json:"cases"`
+}
+
+const (
+	caseHandlingReplace           = "replace"
+	caseHandlingBumpExistingCases = "bumpExistingCases"
+	caseHandlingBumpImportedCases = "bumpImportedCases"
+)
+
+var caseHandlingModes = []string{caseHandlingReplace, caseHandlingBumpExistingCases, caseHandlingBumpImportedCases}
+
+type exportNote struct {
+	ModID     *string `json:"mod_id"`
+	ModName   *string `json:"mod_name"`
+	Body      string  `json:"body"`
+	CreatedAt string  `json:"created_at"`
+}
+
+func (h *Handler) ExportCases(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ManageAccess) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if !h.limits.allow("export-"+guildID, importExportRateLimit) {
+		return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "A single server can only export data once every 5 minutes"})
+	}
+
+	filename := fmt.Sprintf("export_%s_%s.json", guildID, time.Now().UTC().Format("2006-01-02_15-04-05"))
+
+	w := c.Response()
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+
+	// The document is written out by hand around each case so we never need to
+	// hold more than one of them in memory, indented like JSON.stringify(data, null, 2)
+	if _, err := w.Write([]byte("{\n  \"cases\": [")); err != nil {
+		return err
+	}
+
+	first := true
+	err := h.db.ExportCases(c.Request().Context(), guildID, func(theCase *models.Case) error {
+		serialized, err := json.MarshalIndent(toExportCase(theCase), "    ", "  ")
+		if err != nil {
+			return err
+		}
+
+		sep := ",\n    "
+		if first {
+			sep = "\n    "
+			first = false
+		}
+		if _, err := w.Write([]byte(sep)); err != nil {
+			return err
+		}
+		_, err = w.Write(serialized)
+		return err
+	})
+	if err != nil {
+		// Headers are already out, so all we can do is cut the response short
+		c.Logger().Error("couldn't export cases", "error", err.Error(), "guildID", guildID, "userID", userID)
+		return err
+	}
+
+	end := "\n  ]\n}"
+	if first {
+		end = "]\n}"
+	}
+	_, err = w.Write([]byte(end))
+	return err
+}
+
+func toExportCase(theCase *models.Case) exportCase {
+	notes := make([]exportNote, 0, len(theCase.Notes))
+	for _, note := range theCase.Notes {
+		notes = append(notes, exportNote{
+			ModID:     note.ModID,
+			ModName:   note.ModName,
+			Body:      note.Body,
+			CreatedAt: note.CreatedAt.UTC().Format(exportTimeFormat),
+		})
+	}
+
+	return exportCase{
+		CaseNumber:   theCase.CaseNumber,
+		UserID:       theCase.UserID,
+		UserName:     theCase.UserName,
+		ModID:        theCase.ModID,
+		ModName:      theCase.ModName,
+		Type:         int(theCase.Type),
+		CreatedAt:    theCase.CreatedAt.UTC().Format(exportTimeFormat),
+		IsHidden:     theCase.IsHidden,
+		PPID:         theCase.PPID,
+		PPName:       theCase.PPName,
+		LogMessageID: theCase.LogMessageID,
+		Notes:        notes,
+	}
+}
+
+func (h *Handler) PreImport(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ManageAccess) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	minNumber, maxNumber, err := h.db.GetCaseNumberRange(c.Request().Context(), guildID)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve case numbers", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"minCaseNumber": minNumber, "maxCaseNumber": maxNumber})
+}
+
+func (h *Handler) ImportCases(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ManageAccess) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var body struct {
+		Data             *importData `json:"data"`
+		CaseHandlingMode string      `json:"caseHandlingMode"`
+		DryRun           bool        `json:"dryRun"`
+	}
+	if err := c.Bind(&body); err != nil || body.Data == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid import data format"})
+	}
+
+	// Dry runs don't touch anything, so they shouldn't use up the import window
+	if !body.DryRun && !h.limits.allow("import-"+guildID, importExportRateLimit) {
+		return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "A single server can only import data once every 5 minutes"})
+	}
+
+	if !slices.Contains(caseHandlingModes, body.CaseHandlingMode) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid case handling mode"})
+	}
+
+	cases, err := parseImportCases(guildID, body.Data.Cases)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Dry runs can be repeated as often as anyone likes, so they only take a look at
+	// the case numbers rather than locking them, which would hold up the bot
+	if body.DryRun {
+		existing, err := h.db.GetCaseNumbers(c.Request().Context(), guildID)
+		if err != nil {
+			c.Logger().Error("couldn't retrieve existing case numbers", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		}
+		return c.JSON(http.StatusOK, planImport(body.CaseHandlingMode, cases, existing))
+	}
+
+	tx, err := h.db.Tx()
+	if err != nil {
+		c.Logger().Error("cannot start transaction to import cases", "tx_err", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	defer tx.Rollback()
+
+	existing, err := h.db.LockCaseNumbers(tx, c.Request().Context(), guildID)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve existing case numbers", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	plan := planImport(body.CaseHandlingMode, cases, existing)
+
+	switch body.CaseHandlingMode {
+	case caseHandlingReplace:
+		_, err = h.db.DeleteAllCases(tx, c.Request().Context(), guildID)
+	case caseHandlingBumpExistingCases:
+		_, err = h.db.BumpCaseNumbers(tx, c.Request().Context(), guildID, plan.bumpExistingBy)
+	}
+	if err != nil {
+		c.Logger().Error("couldn't prepare existing cases for import", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	notes := 0
+	for i, theCase := range cases {
+		theCase.CaseNumber = plan.Mapping[i].To
+		if _, err := h.db.InsertCase(tx, c.Request().Context(), &theCase); err != nil {
+			c.Logger().Error("couldn't import case", "sql_error", err.Error(), "guildID", guildID, "userID", userID, "caseNumber", theCase.CaseNumber)
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Could not import case %d, nothing was imported", theCase.CaseNumber)})
+		}
+		notes += len(theCase.Notes)
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.Logger().Error("couldn't commit import transaction", "tx_err", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "IMPORT_CASES", map[string]any{
+		"case_handling_mode": body.CaseHandlingMode,
+		"imported_cases":     len(cases),
+		"imported_notes":     notes,
+		"deleted_cases":      plan.DeletedCases,
+		"bumped_cases":       plan.BumpedCases,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok"})
+}
+
+type caseNumberMapping struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// importPlan describes what an import will do to the guild's cases. It's what
+// dry runs report, and what the real import follows.
+type importPlan struct {
+	CaseHandlingMode string              `json:"caseHandlingMode"`
+	ImportedCases    int                 `json:"importedCases"`
+	Mapping          []caseNumberMapping `json:"mapping"`
+	MinCaseNumber    int                 `json:"minCaseNumber"`
+	MaxCaseNumber    int                 `json:"maxCaseNumber"`
+	DeletedCases     int                 `json:"deletedCases"`
+	BumpedCases      int                 `json:"bumpedCases"`
+	// Collisions are the imported case numbers the guild already has, which are
+	// what the case handling mode has to sort out
+	Collisions []int `json:"collisions"`
+
+	bumpExistingBy int
+}
+
+// planImport works out the final case numbers for an import, given the case
+// numbers already in the guild (in ascending order)
+func planImport(mode string, cases []models.Case, existing []int) importPlan {
+	plan := importPlan{
+		CaseHandlingMode: mode,
+		ImportedCases:    len(cases),
+		Mapping:          make([]caseNumberMapping, 0, len(cases)),
+		Collisions:       []int{},
+	}
+
+	for _, theCase := range cases {
+		if _, found := slices.BinarySearch(existing, theCase.CaseNumber); found {
+			plan.Collisions = append(plan.Collisions, theCase.CaseNumber)
+		}
+	}
+	slices.Sort(plan.Collisions)
+
+	remaining := existing
+	offset := 0
+	switch mode {
+	case caseHandlingReplace:
+		plan.DeletedCases = len(existing)
+		remaining = nil
+	case caseHandlingBumpExistingCases:
+		for _, theCase := range cases {
+			plan.bumpExistingBy = max(plan.bumpExistingBy, theCase.CaseNumber)
+		}
+		plan.BumpedCases = len(existing)
+		remaining = make([]int, len(existing))
+		for i, n := range existing {
+			remaining[i] = n + plan.bumpExistingBy
+		}
+	case caseHandlingBumpImportedCases:
+		if len(existing) > 0 {
+			offset = existing[len(existing)-1]
+		}
+	}
+
+	if len(remaining) > 0 {
+		plan.MinCaseNumber, plan.MaxCaseNumber = remaining[0], remaining[len(remaining)-1]
+	}
+
+	for _, theCase := range cases {
+		to := theCase.CaseNumber + offset
+		plan.Mapping = append(plan.Mapping, caseNumberMapping{From: theCase.CaseNumber, To: to})
+
+		if plan.MinCaseNumber == 0 || to < plan.MinCaseNumber {
+			plan.MinCaseNumber = to
+		}
+		plan.MaxCaseNumber = max(plan.MaxCaseNumber, to)
+	}
+
+	return plan
+}
+
+// parseImportCases validates the imported cases and turns them into models ready to insert
+func parseImportCases(guildID string, data []exportCase) ([]models.Case, error) {
+	cases := make([]models.Case, 0, len(data))
+	seen := make(map[int]bool, len(data))
+
+	for i, theCase := range data {
+		if seen[theCase.CaseNumber] {
+			return nil, fmt.Errorf("Duplicate case number: %d", theCase.CaseNumber)
+		}
+		seen[theCase.CaseNumber] = true
+
+		if theCase.CaseNumber < 1 {
+			return nil, fmt.Errorf("Invalid import data format: invalid case_number at /cases/%d", i)
+		}
+		if theCase.UserID == "" {
+			return nil, fmt.Errorf("Invalid import data format: missing user_id at /cases/%d", i)
+		}
+		if theCase.Type < int(models.CaseTypeBan) || theCase.Type > int(models.CaseTypeSoftban) {
+			return nil, fmt.Errorf("Invalid import data format: invalid type at /cases/%d", i)
+		}
+		createdAt, err := parseExportTime(theCase.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid import data format: invalid created_at at /cases/%d", i)
+		}
+
+		notes := make([]models.CaseNote, 0, len(theCase.Notes))
+		for j, note := range theCase.Notes {
+			noteCreatedAt, err := parseExportTime(note.CreatedAt)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid import data format: invalid created_at at /cases/%d/notes/%d", i, j)
+			}
+			notes = append(notes, models.CaseNote{
+				ModID:     note.ModID,
+				ModName:   note.ModName,
+				Body:      note.Body,
+				CreatedAt: noteCreatedAt,
+			})
+		}
+
+		cases = append(cases, models.Case{
+			GuildID:      guildID,
+			CaseNumber:   theCase.CaseNumber,
+			UserID:       theCase.UserID,
+			UserName:     theCase.UserName,
+			ModID:        theCase.ModID,
+			ModName:      theCase.ModName,
+			Type:         models.CaseType(theCase.Type),
+			CreatedAt:    createdAt,
+			IsHidden:     theCase.IsHidden,
+			PPID:         theCase.PPID,
+			PPName:       theCase.PPName,
+			LogMessageID: theCase.LogMessageID,
+			Notes:        notes,
+		})
+	}
+
+	return cases, nil
+}
+
+// parseExportTime accepts our own export format, plus RFC 3339 for hand-made files
+func parseExportTime(s string) (time.Time, error) {
+	if t, err := time.Parse(exportTimeFormat, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
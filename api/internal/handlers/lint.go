@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+	"github.com/owdiscord/athena/api/internal/permissions"
+	"gopkg.in/yaml.v3"
+)
+
+// LintConfig checks a candidate config without saving it, using the same validation
+// as SaveConfig and adding warnings for things that are allowed but look wrong, such
+// as IDs of roles and channels that no longer exist. Both come back with their
+// positions so the editor can mark them inline.
+func (h *Handler) LintConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var body struct {
+		Config *string `json:"config"`
+	}
+	if err := c.Bind(&body); err != nil || body.Config == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
+	}
+
+	errs, warnings := []configs.Error{}, []configs.Error{}
+
+	root, err := configs.Parse(*body.Config)
+	if err != nil {
+		errs = append(errs, err.(configs.Error))
+	} else {
+		errs = append(errs, configs.ValidateGuildConfig(root)...)
+		warnings = append(warnings, configs.LintGuildConfig(root)...)
+
+		unknown, err := h.unknownReferences(c.Request().Context(), guildID, root)
+		if err != nil {
+			// Not worth failing the lint over, the rest of it is still useful
+			c.Logger().Error("couldn't check config references", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		}
+		warnings = append(warnings, unknown...)
+
+		if name := configs.Extends(root); name != "" {
+			template, err := h.db.GetActiveConfig(c.Request().Context(), templateKeyPrefix+name)
+			if err != nil {
+				c.Logger().Error("couldn't retrieve config template", "sql_error", err.Error(), "template", name, "guildID", guildID, "userID", userID)
+			} else if template == nil {
+				warnings = append(warnings, configs.Error{Path: "extends", Message: fmt.Sprintf("there's no template called %s, so nothing will be inherited", name)})
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"valid": len(errs) == 0, "errors": errs, "warnings": warnings})
+}
+
+// unknownReferences checks the role, channel and user IDs in a config against the
+// bot's snapshot of the guild and its member cache, returning a warning for each
+// one that isn't there. Without a snapshot there's nothing to check against, so
+// nothing is reported.
+func (h *Handler) unknownReferences(ctx context.Context, guildID string, root *yaml.Node) ([]configs.Error, error) {
+	refs := configs.ExtractReferences(root)
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	snapshot, err := h.db.GetGuildSnapshot(ctx, guildID)
+	if err != nil || snapshot == nil {
+		return nil, err
+	}
+
+	// The @everyone role shares the guild's ID
+	roles := map[string]bool{guildID: true}
+	for _, id := range snapshot.RoleIDs {
+		roles[id] = true
+	}
+	channels := make(map[string]bool, len(snapshot.ChannelIDs))
+	for _, id := range snapshot.ChannelIDs {
+		channels[id] = true
+	}
+
+	var userIDs []string
+	for _, ref := range refs {
+		if ref.Kind == configs.ReferenceUser || (ref.Kind == configs.ReferenceRoleOrUser && !roles[ref.ID]) {
+			userIDs = append(userIDs, ref.ID)
+		}
+	}
+	members, err := h.db.GetCachedMemberIDs(ctx, guildID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []configs.Error
+	for _, ref := range refs {
+		var known bool
+		switch ref.Kind {
+		case configs.ReferenceRole:
+			known = roles[ref.ID]
+		case configs.ReferenceChannel:
+			known = channels[ref.ID]
+		case configs.ReferenceUser:
+			known = members[ref.ID]
+		case configs.ReferenceRoleOrUser:
+			known = roles[ref.ID] || members[ref.ID]
+		}
+		if !known {
+			warnings = append(warnings, configs.Error{
+				Path:    ref.Path,
+				Message: fmt.Sprintf("%s isn't a %s in this server", ref.ID, ref.Kind),
+				Line:    ref.Line,
+				Column:  ref.Column,
+			})
+		}
+	}
+	return warnings, nil
+}
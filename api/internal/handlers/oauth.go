@@ -2,27 +2,54 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v5"
 	"github.com/owdiscord/athena/api/internal/services/discord"
 )
 
 func (h *Handler) OAuthLogin(c *echo.Context) error {
-	return c.Redirect(http.StatusTemporaryRedirect, h.discord.AuthURL(""))
+	nonce, err := newOAuthNonce()
+	if err != nil {
+		c.Logger().Error("couldn't generate oauth state", "err", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	verifier, err := discord.NewPKCEVerifier()
+	if err != nil {
+		c.Logger().Error("couldn't generate pkce verifier", "err", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	state := oauthState{nonce: nonce, verifier: verifier, expiresAt: time.Now().Add(oauthStateTTL)}
+	setOAuthStateCookie(c, h.signOAuthState(state), state.expiresAt)
+
+	return c.Redirect(http.StatusTemporaryRedirect, h.discord.AuthURL(nonce, verifier))
 }
 
 func (h *Handler) OAuthCallback(c *echo.Context) error {
+	// The state is single use, whatever happens next
+	cookie, _ := c.Cookie(oauthStateCookie)
+	setOAuthStateCookie(c, "", time.Time{})
+
+	if cookie == nil {
+		return c.Redirect(http.StatusTemporaryRedirect, dashboardURL("/login-callback?error=noAccess&msg=noState"))
+	}
+	state, err := h.parseOAuthState(cookie.Value, c.QueryParam("state"), time.Now())
+	if err != nil {
+		return c.Redirect(http.StatusTemporaryRedirect, dashboardURL("/login-callback?error=noAccess&msg=badState"))
+	}
+
 	code := c.QueryParam("code")
 	if code == "" {
 		return c.Redirect(http.StatusTemporaryRedirect, dashboardURL("/login-callback?error=noAccess&msg=noCode"))
 	}
 
-	resp, err := h.discord.Exchange(code)
+	resp, err := h.discord.Exchange(c.Request().Context(), code, state.verifier)
 	if err != nil {
 		return c.Redirect(http.StatusTemporaryRedirect, dashboardURL("/login-callback?error=noAccess&msg=noExchange"))
 	}
 
-	user, err := discord.GetUser(resp.AccessToken)
+	user, err := h.discord.GetUser(c.Request().Context(), resp.AccessToken)
 	if err != nil {
 		return c.Redirect(http.StatusTemporaryRedirect, dashboardURL("/login-callback?error=noAccess&msg=noToken"))
 	}
@@ -34,7 +61,7 @@ func (h *Handler) OAuthCallback(c *echo.Context) error {
 		return c.Redirect(http.StatusTemporaryRedirect, dashboardURL("/login-callback?error=noAccess&msg=noPerms"))
 	}
 
-	apiKey, err := h.db.CreateAPIKey(c.Request().Context(), user.ID)
+	apiKey, err := h.db.CreateAPIKey(c.Request().Context(), user.ID, c.Request().UserAgent(), ipPrefix(c.RealIP()))
 	if err != nil {
 		c.Logger().Error("no_api_key", "db_err", err)
 		return c.Redirect(http.StatusTemporaryRedirect, dashboardURL("/login-callback?error=noAccess&msg=cantMakeKey"))
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/services/discord"
+)
+
+// fakeDiscord stands in for Discord's OAuth endpoints. Token exchanges are recorded
+// and answered with a token, while fetching the user always fails, so a callback
+// that gets that far stops before it needs the database.
+type fakeDiscord struct {
+	*httptest.Server
+	mu        sync.Mutex
+	exchanges []url.Values
+}
+
+func newFakeDiscord(t *testing.T) *fakeDiscord {
+	f := &fakeDiscord{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/token":
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("couldn't parse token request: %v", err)
+			}
+			f.mu.Lock()
+			f.exchanges = append(f.exchanges, r.PostForm)
+			f.mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"access","token_type":"Bearer","expires_in":3600}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeDiscord) tokenRequests() []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]url.Values(nil), f.exchanges...)
+}
+
+func newOAuthTestHandler(key, discordURL string) *Handler {
+	return &Handler{
+		key: key,
+		discord: &discord.Config{
+			ClientID:     "client",
+			ClientSecret: "secret",
+			RedirectURI:  "http://localhost/api/auth/oauth-callback",
+			Scopes:       []string{"identify"},
+			BaseURL:      discordURL,
+		},
+		limits: newRateLimiter(),
+	}
+}
+
+// callback runs OAuthCallback with the given state cookie, if any, and query,
+// returning the msg the user is redirected back to the dashboard with
+func callback(t *testing.T, h *Handler, cookie *http.Cookie, query string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oauth-callback?"+query, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	if err := h.OAuthCallback(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("OAuthCallback returned %v", err)
+	}
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("OAuthCallback responded %d, want a redirect", rec.Code)
+	}
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("bad redirect %q: %v", rec.Header().Get("Location"), err)
+	}
+	return location.Query().Get("msg")
+}
+
+func TestOAuthLoginUsesPKCE(t *testing.T) {
+	fake := newFakeDiscord(t)
+	h := newOAuthTestHandler("key", fake.URL)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/login", nil)
+	rec := httptest.NewRecorder()
+	if err := h.OAuthLogin(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("OAuthLogin returned %v", err)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie {
+		t.Fatalf("OAuthLogin set cookies %v, want just %s", cookies, oauthStateCookie)
+	}
+	if !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Errorf("state cookie should be HttpOnly and SameSite=Lax, got %+v", cookies[0])
+	}
+
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("bad redirect %q: %v", rec.Header().Get("Location"), err)
+	}
+	if !strings.HasPrefix(authURL.String(), fake.URL+"/oauth2/authorize?") {
+		t.Errorf("redirected to %s, want Discord's authorize endpoint", authURL)
+	}
+
+	params := authURL.Query()
+	state, err := h.parseOAuthState(cookies[0].Value, params.Get("state"), time.Now())
+	if err != nil {
+		t.Fatalf("the state cookie doesn't match the state sent to Discord: %v", err)
+	}
+	if params.Get("code_challenge_method") != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", params.Get("code_challenge_method"))
+	}
+	if want := discord.PKCEChallenge(state.verifier); params.Get("code_challenge") != want {
+		t.Errorf("code_challenge = %q, want %q", params.Get("code_challenge"), want)
+	}
+	if strings.Contains(authURL.RawQuery, state.verifier) {
+		t.Error("the PKCE verifier was sent to the authorize endpoint")
+	}
+
+	// Finishing the login sends the verifier from the cookie along with the code
+	msg := callback(t, h, cookies[0], url.Values{"state": {params.Get("state")}, "code": {"the-code"}}.Encode())
+	if msg != "noToken" {
+		t.Fatalf("callback failed with %q, want it to get as far as fetching the user", msg)
+	}
+
+	exchanges := fake.tokenRequests()
+	if len(exchanges) != 1 {
+		t.Fatalf("made %d token exchanges, want 1", len(exchanges))
+	}
+	if got := exchanges[0].Get("code"); got != "the-code" {
+		t.Errorf("exchanged code %q, want the-code", got)
+	}
+	if got := exchanges[0].Get("code_verifier"); got != state.verifier {
+		t.Errorf("exchanged with code_verifier %q, want %q", got, state.verifier)
+	}
+}
+
+func TestOAuthCallbackRejectsBadState(t *testing.T) {
+	now := time.Now()
+	h := newOAuthTestHandler("key", "")
+	valid := oauthState{nonce: "nonce", verifier: "verifier", expiresAt: now.Add(oauthStateTTL)}
+	signed := h.signOAuthState(valid)
+	parts := strings.Split(signed, ".")
+
+	tests := []struct {
+		name   string
+		cookie string
+		state  string
+		msg    string
+	}{
+		{"no cookie", "", "nonce", "noState"},
+		{"no state parameter", signed, "", "badState"},
+		{"mismatched state", signed, "other-nonce", "badState"},
+		{"expired", h.signOAuthState(oauthState{nonce: "nonce", verifier: "verifier", expiresAt: now.Add(-time.Second)}), "nonce", "badState"},
+		{"signed with another key", newOAuthTestHandler("other-key", "").signOAuthState(valid), "nonce", "badState"},
+		{"tampered nonce", "other-nonce." + strings.Join(parts[1:], "."), "other-nonce", "badState"},
+		{"tampered verifier", parts[0] + ".other-verifier." + strings.Join(parts[2:], "."), "nonce", "badState"},
+		{"tampered expiry", strings.Join(parts[:2], ".") + ".9999999999." + parts[3], "nonce", "badState"},
+		{"tampered signature", strings.Join(parts[:3], ".") + ".AAAA", "nonce", "badState"},
+		{"missing signature", strings.Join(parts[:3], "."), "nonce", "badState"},
+		{"garbage", "garbage", "nonce", "badState"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDiscord(t)
+			h.discord.BaseURL = fake.URL
+
+			var cookie *http.Cookie
+			if tt.cookie != "" {
+				cookie = &http.Cookie{Name: oauthStateCookie, Value: tt.cookie}
+			}
+			query := url.Values{"state": {tt.state}, "code": {"the-code"}}.Encode()
+			if msg := callback(t, h, cookie, query); msg != tt.msg {
+				t.Errorf("callback failed with %q, want %q", msg, tt.msg)
+			}
+			if n := len(fake.tokenRequests()); n != 0 {
+				t.Errorf("made %d token exchanges with a bad state, want none", n)
+			}
+		})
+	}
+}
+
+func TestParseOAuthStateExpiry(t *testing.T) {
+	h := newOAuthTestHandler("key", "")
+	expiresAt := time.Unix(1_700_000_000, 0)
+	signed := h.signOAuthState(oauthState{nonce: "nonce", verifier: "verifier", expiresAt: expiresAt})
+
+	if _, err := h.parseOAuthState(signed, "nonce", expiresAt.Add(-time.Second)); err != nil {
+		t.Errorf("state was rejected a second before it expired: %v", err)
+	}
+	if _, err := h.parseOAuthState(signed, "nonce", expiresAt); err != errInvalidOAuthState {
+		t.Errorf("state was accepted at its expiry time, err = %v", err)
+	}
+}
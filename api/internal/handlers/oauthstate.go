@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	oauthStateCookie = "athena_oauth_state"
+	// oauthStateTTL is how long the user has to get through Discord's consent screen
+	oauthStateTTL = 10 * time.Minute
+)
+
+var errInvalidOAuthState = errors.New("invalid oauth state")
+
+// oauthState ties a login started in this browser to the callback that finishes it.
+// The nonce goes to Discord as the state parameter and comes back in the callback,
+// while the whole thing is kept in a cookie signed with our key. The PKCE verifier
+// rides along in the cookie, since only this browser should be able to use it.
+type oauthState struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+func (h *Handler) signOAuthState(s oauthState) string {
+	payload := s.nonce + "." + s.verifier + "." + strconv.FormatInt(s.expiresAt.Unix(), 10)
+	return payload + "." + h.oauthStateSignature(payload)
+}
+
+// parseOAuthState checks a state cookie's signature and expiry, and that it's the
+// one the callback's state parameter belongs to
+func (h *Handler) parseOAuthState(cookie, state string, now time.Time) (oauthState, error) {
+	parts := strings.Split(cookie, ".")
+	if len(parts) != 4 {
+		return oauthState{}, errInvalidOAuthState
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(h.oauthStateSignature(payload))) {
+		return oauthState{}, errInvalidOAuthState
+	}
+
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || !now.Before(time.Unix(expiry, 0)) {
+		return oauthState{}, errInvalidOAuthState
+	}
+
+	if state == "" || !hmac.Equal([]byte(parts[0]), []byte(state)) {
+		return oauthState{}, errInvalidOAuthState
+	}
+
+	return oauthState{nonce: parts[0], verifier: parts[1], expiresAt: time.Unix(expiry, 0)}, nil
+}
+
+func (h *Handler) oauthStateSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte("oauth-state:"+h.key))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newOAuthNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// setOAuthStateCookie stores the state for the callback, or clears it when value
+// is empty. It has to survive the redirect back from Discord, which is a top-level
+// navigation from another site, so it's SameSite=Lax rather than Strict.
+func setOAuthStateCookie(c *echo.Context, value string, expiresAt time.Time) {
+	cookie := &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     "/api/auth",
+		HttpOnly: true,
+		Secure:   c.Request().TLS != nil || c.Request().Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expiresAt
+	}
+	c.SetCookie(cookie)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+	"github.com/owdiscord/athena/api/internal/permissions"
+)
+
+// PatchConfig applies an RFC 6902 JSON Patch to the guild's config, addressing it
+// as if the YAML were JSON, and saves the result as a new revision
+func (h *Handler) PatchConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.EditConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get("Content-Type")); mediaType != "application/json-patch+json" {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "expected application/json-patch+json")
+	}
+
+	var ops []configs.PatchOperation
+	if err := json.NewDecoder(c.Request().Body).Decode(&ops); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid patch")
+	}
+
+	base, err := baseRevision(c, nil)
+	if err != nil {
+		return err
+	}
+
+	key := "guild-" + guildID
+	current, err := h.db.GetActiveConfig(c.Request().Context(), key)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve guild", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	source := ""
+	var currentID int64
+	if current != nil {
+		source, currentID = current.Config, current.ID
+	}
+	// The patch applies to what's live now, so without an If-Match at least make
+	// sure that doesn't change under us
+	if base == nil {
+		base = &currentID
+	}
+
+	doc, err := configs.ParseDocument(source)
+	if err != nil {
+		return configErrors(c, http.StatusUnprocessableEntity, []configs.Error{err.(configs.Error)})
+	}
+	if err := configs.ApplyPatch(doc, ops); err != nil {
+		return configErrors(c, http.StatusUnprocessableEntity, []configs.Error{err.(configs.Error)})
+	}
+
+	config, err := configs.Encode(doc, configs.Indentation(doc))
+	if err != nil {
+		c.Logger().Error("couldn't encode patched config", "yaml_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	// Re-encoding can tidy up the formatting, which isn't worth a revision on its own
+	if current != nil && (len(ops) == 0 || config == current.Config) {
+		c.Response().Header().Set("ETag", revisionETag(current.ID))
+		return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": current.ID})
+	}
+
+	if status, errs := validateGuildConfig(config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	id, err := h.publishConfig(c, key, config, userID, base, nil)
+	if conflict, ok := err.(*configConflictError); ok {
+		return h.configConflict(c, key, *base, conflict.current)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "PATCH_CONFIG", map[string]any{
+		"operations":  ops,
+		"revision_id": id,
+	})
+
+	c.Response().Header().Set("ETag", revisionETag(id))
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": id})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+	"github.com/owdiscord/athena/api/internal/permissions"
+	"gopkg.in/yaml.v3"
+)
+
+func (h *Handler) GetPluginConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+	plugin := c.Param("plugin")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if !configs.GuildPlugins[plugin] {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown plugin")
+	}
+
+	config, err := h.db.GetActiveConfig(c.Request().Context(), "guild-"+guildID)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve guild", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if config == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "plugin is not configured")
+	}
+
+	doc, err := configs.ParseDocument(config.Config)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, map[string][]string{"errors": {err.Error()}})
+	}
+	options := configs.PluginOptions(doc, plugin)
+	if options == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "plugin is not configured")
+	}
+
+	pluginConfig, err := configs.Encode(options, configs.Indentation(doc))
+	if err != nil {
+		c.Logger().Error("couldn't encode plugin config", "yaml_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	c.Response().Header().Set("ETag", revisionETag(config.ID))
+	return c.JSON(http.StatusOK, map[string]any{"plugin": plugin, "config": pluginConfig, "revision": config.ID})
+}
+
+// SavePluginConfig replaces plugins.<plugin> in the guild's config, leaving the
+// rest of the file as it was, and saves the result as a new revision
+func (h *Handler) SavePluginConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+	plugin := c.Param("plugin")
+
+	if !h.hasPermission(c, userID, guildID, permissions.EditConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if !configs.GuildPlugins[plugin] {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown plugin")
+	}
+
+	var body struct {
+		Config       *string `json:"config"`
+		BaseRevision *int64  `json:"baseRevision"`
+	}
+	if err := c.Bind(&body); err != nil || body.Config == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
+	}
+
+	base, err := baseRevision(c, body.BaseRevision)
+	if err != nil {
+		return err
+	}
+
+	options, err := configs.Parse(*body.Config)
+	if err != nil {
+		parseErr := err.(configs.Error)
+		parseErr.Path = "plugins." + plugin
+		return configErrors(c, http.StatusBadRequest, []configs.Error{parseErr})
+	}
+	if options == nil {
+		options = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}
+	}
+
+	key := "guild-" + guildID
+	current, err := h.db.GetActiveConfig(c.Request().Context(), key)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve guild", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	source := ""
+	var currentID int64
+	if current != nil {
+		source, currentID = current.Config, current.ID
+	}
+	// The edit is made against what's live now, so if the caller didn't say what
+	// they started from, at least make sure that doesn't change under us
+	if base == nil {
+		base = &currentID
+	}
+
+	config, err := configs.ReplacePlugin(source, plugin, options)
+	if err != nil {
+		if configErr, ok := err.(configs.Error); ok {
+			return configErrors(c, http.StatusUnprocessableEntity, []configs.Error{configErr})
+		}
+		c.Logger().Error("couldn't edit plugin config", "yaml_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	if current != nil && config == current.Config {
+		c.Response().Header().Set("ETag", revisionETag(current.ID))
+		return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": current.ID})
+	}
+
+	return h.saveGuildConfig(c, key, config, userID, base)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimitPruneInterval is how often keys whose window has passed are dropped,
+// so the limiter doesn't keep every key it's ever seen
+const rateLimitPruneInterval = time.Minute
+
+// rateLimiter is a port of the backend's rateLimit middleware: each key may only
+// be used once per window. It lives in memory, so limits reset on restart.
+type rateLimiter struct {
+	mu sync.Mutex
+	// until is when each key may next be used
+	until  map[string]time.Time
+	pruned time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{until: make(map[string]time.Time)}
+}
+
+// allow reports whether key hasn't been used within the window, and if so,
+// records this use
+func (r *rateLimiter) allow(key string, window time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.pruned) >= rateLimitPruneInterval {
+		for k, until := range r.until {
+			if !now.Before(until) {
+				delete(r.until, k)
+			}
+		}
+		r.pruned = now
+	}
+
+	if until, ok := r.until[key]; ok && now.Before(until) {
+		return false
+	}
+	r.until[key] = now.Add(window)
+	return true
+}
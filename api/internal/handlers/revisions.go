@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+	"github.com/owdiscord/athena/api/internal/models"
+	"github.com/owdiscord/athena/api/internal/permissions"
+)
+
+const (
+	defaultRevisionPageSize = 25
+	maxRevisionPageSize     = 100
+)
+
+func (h *Handler) ListConfigRevisions(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	return h.listRevisions(c, "guild-"+guildID, userID)
+}
+
+// listRevisions responds with a page of the revisions under key, newest first,
+// going by the limit and cursor query parameters
+func (h *Handler) listRevisions(c *echo.Context, key, userID string) error {
+	limit := defaultRevisionPageSize
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		limit = min(n, maxRevisionPageSize)
+	}
+
+	var cursor int64
+	if v := c.QueryParam("cursor"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+		}
+		cursor = n
+	}
+
+	// Fetch one extra row so we know whether there's another page
+	revisions, err := h.db.GetConfigRevisions(c.Request().Context(), key, cursor, limit+1)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config revisions", "sql_error", err.Error(), "key", key, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	var nextCursor *int64
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+		nextCursor = &revisions[limit-1].ID
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"revisions": revisions, "nextCursor": nextCursor})
+}
+
+func (h *Handler) GetConfigRevision(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid revision id")
+	}
+
+	revision, err := h.db.GetConfigRevision(c.Request().Context(), "guild-"+guildID, id)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config revision", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if revision == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	return c.JSON(http.StatusOK, revision)
+}
+
+func (h *Handler) RestoreConfigRevision(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.EditConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid revision id")
+	}
+
+	key := "guild-" + guildID
+	revision, err := h.db.GetConfigRevision(c.Request().Context(), key, id)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config revision", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if revision == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	current, err := h.db.GetActiveConfig(c.Request().Context(), key)
+	if err == nil && current != nil && revision.Config == current.Config {
+		return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": current.ID})
+	}
+
+	// Old revisions were saved under whatever rules applied at the time, so they
+	// have to pass today's validation before going live again
+	if status, errs := validateGuildConfig(revision.Config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	newID, err := h.publishConfig(c, key, revision.Config, userID, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "RESTORE_CONFIG", map[string]any{
+		"source_revision_id": revision.ID,
+		"revision_id":        newID,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": newID})
+}
+
+func (h *Handler) DiffConfigRevisions(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	mode := c.QueryParamOr("mode", "unified")
+	if mode != "unified" && mode != "structural" {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid mode")
+	}
+
+	fromID, err := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+	}
+
+	key := "guild-" + guildID
+	from, err := h.db.GetConfigRevision(c.Request().Context(), key, fromID)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config revision", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if from == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "from revision not found")
+	}
+
+	// Without a "to" we compare against whatever is live right now
+	var to *models.ConfigRevision
+	if v := c.QueryParam("to"); v != "" {
+		toID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid to")
+		}
+		to, err = h.db.GetConfigRevision(c.Request().Context(), key, toID)
+		if err != nil {
+			c.Logger().Error("couldn't retrieve config revision", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		}
+	} else {
+		active, err := h.db.GetActiveConfig(c.Request().Context(), key)
+		if err != nil {
+			c.Logger().Error("couldn't retrieve active config", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		}
+		if active != nil {
+			to = &models.ConfigRevision{ID: active.ID, Config: active.Config}
+		}
+	}
+	if to == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "to revision not found")
+	}
+
+	result := map[string]any{"from": from.ID, "to": to.ID, "mode": mode}
+
+	if mode == "unified" {
+		result["diff"] = configs.UnifiedDiff(
+			fmt.Sprintf("revision %d", from.ID),
+			fmt.Sprintf("revision %d", to.ID),
+			from.Config, to.Config,
+		)
+		return c.JSON(http.StatusOK, result)
+	}
+
+	fromRoot, err := configs.Parse(from.Config)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, map[string][]string{"errors": {fmt.Sprintf("revision %d: %s", from.ID, err)}})
+	}
+	toRoot, err := configs.Parse(to.Config)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, map[string][]string{"errors": {fmt.Sprintf("revision %d: %s", to.ID, err)}})
+	}
+
+	result["changes"] = configs.StructuralDiff(fromRoot, toRoot)
+	return c.JSON(http.StatusOK, result)
+}
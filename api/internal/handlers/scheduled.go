@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+	"github.com/owdiscord/athena/api/internal/models"
+	"github.com/owdiscord/athena/api/internal/permissions"
+)
+
+func (h *Handler) ListScheduledConfigs(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	status := c.QueryParam("status")
+	switch status {
+	case "", models.ScheduleScheduled, models.SchedulePublished, models.ScheduleReverted, models.ScheduleCancelled, models.ScheduleFailed:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+	}
+
+	scheduled, err := h.db.GetScheduledConfigs(c.Request().Context(), "guild-"+guildID, status)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve scheduled configs", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"scheduled": scheduled})
+}
+
+func (h *Handler) GetScheduledConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scheduled config id")
+	}
+
+	scheduled, err := h.db.GetScheduledConfig(c.Request().Context(), "guild-"+guildID, id)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve scheduled config", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if scheduled == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	return c.JSON(http.StatusOK, scheduled)
+}
+
+func (h *Handler) ScheduleConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.EditConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var body struct {
+		Config    *string    `json:"config"`
+		PublishAt *time.Time `json:"publishAt"`
+		RevertAt  *time.Time `json:"revertAt"`
+	}
+	if err := c.Bind(&body); err != nil || body.Config == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
+	}
+	if body.PublishAt == nil || !body.PublishAt.After(time.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, "publishAt must be in the future")
+	}
+	if body.RevertAt != nil && !body.RevertAt.After(*body.PublishAt) {
+		return echo.NewHTTPError(http.StatusBadRequest, "revertAt must be after publishAt")
+	}
+
+	config := strings.TrimSpace(*body.Config) + "\n"
+	if status, errs := validateGuildConfig(config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+
+	publishAt := body.PublishAt.UTC()
+	revertAt := body.RevertAt
+	if revertAt != nil {
+		utc := revertAt.UTC()
+		revertAt = &utc
+	}
+
+	id, err := h.db.CreateScheduledConfig(c.Request().Context(), "guild-"+guildID, config, publishAt, revertAt, userID)
+	if err != nil {
+		c.Logger().Error("couldn't schedule config", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "SCHEDULE_CONFIG", map[string]any{
+		"scheduled_config_id": id,
+		"publish_at":          publishAt,
+		"revert_at":           revertAt,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "scheduled": id})
+}
+
+func (h *Handler) CancelScheduledConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.EditConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scheduled config id")
+	}
+
+	cancelled, err := h.db.CancelScheduledConfig(c.Request().Context(), "guild-"+guildID, id)
+	if err != nil {
+		c.Logger().Error("couldn't cancel scheduled config", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if !cancelled {
+		return echo.NewHTTPError(http.StatusNotFound, "no unpublished scheduled config with that id")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "CANCEL_SCHEDULED_CONFIG", map[string]any{
+		"scheduled_config_id": id,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok"})
+}
+
+// RunScheduledConfigs publishes every scheduled config whose time has come, then
+// reverts the ones whose revert time has passed. main calls this on a ticker.
+func (h *Handler) RunScheduledConfigs(ctx context.Context) {
+	due, err := h.db.GetDueScheduledConfigs(ctx)
+	if err != nil {
+		slog.Error("couldn't retrieve due scheduled configs", "sql_error", err.Error())
+	}
+	for i := range due {
+		h.publishScheduledConfig(ctx, &due[i])
+	}
+
+	reverts, err := h.db.GetDueScheduledReverts(ctx)
+	if err != nil {
+		slog.Error("couldn't retrieve due scheduled reverts", "sql_error", err.Error())
+	}
+	for i := range reverts {
+		h.revertScheduledConfig(ctx, &reverts[i])
+	}
+}
+
+func (h *Handler) publishScheduledConfig(ctx context.Context, s *models.ScheduledConfig) {
+	guildID := strings.TrimPrefix(s.Key, "guild-")
+
+	// Validation may have changed since the config was scheduled
+	if _, errs := validateGuildConfig(s.Config); errs != nil {
+		h.failScheduledConfig(ctx, s, models.ScheduleScheduled, configs.Strings(errs))
+		return
+	}
+
+	tx, err := h.db.Tx()
+	if err != nil {
+		slog.Error("cannot start transaction to publish scheduled config", "tx_err", err, "scheduledConfigID", s.ID)
+		return
+	}
+	defer tx.Rollback()
+
+	previous, err := h.db.LockActiveConfigID(tx, ctx, s.Key)
+	if err != nil {
+		slog.Error("couldn't lock active config", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	if err := h.db.MarkOldConfigsInactive(tx, ctx, s.Key); err != nil {
+		slog.Error("couldn't mark old configs inactive", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	id, err := h.db.SaveConfigRevision(tx, ctx, s.Key, s.Config, s.CreatedBy)
+	if err != nil {
+		slog.Error("couldn't save scheduled config", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+
+	var previousID *int64
+	if previous != 0 {
+		previousID = &previous
+	}
+	published, err := h.db.MarkScheduledConfigPublished(tx, ctx, s.ID, previousID, id)
+	if err != nil {
+		slog.Error("couldn't mark scheduled config published", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	// Cancelled or picked up by another instance in the meantime
+	if !published {
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("couldn't commit scheduled config transaction", "tx_err", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+
+	h.db.AddAuditLog(ctx, guildID, s.CreatedBy, "PUBLISH_SCHEDULED_CONFIG", map[string]any{
+		"scheduled_config_id":  s.ID,
+		"previous_revision_id": previousID,
+		"revision_id":          id,
+	})
+}
+
+func (h *Handler) revertScheduledConfig(ctx context.Context, s *models.ScheduledConfig) {
+	guildID := strings.TrimPrefix(s.Key, "guild-")
+
+	if s.PreviousRevisionID == nil {
+		h.failScheduledConfig(ctx, s, models.SchedulePublished, []string{"There was no config before this one to go back to."})
+		return
+	}
+
+	previous, err := h.db.GetConfigRevision(ctx, s.Key, *s.PreviousRevisionID)
+	if err != nil {
+		slog.Error("couldn't retrieve config revision", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	if previous == nil {
+		h.failScheduledConfig(ctx, s, models.SchedulePublished, []string{"The config this one replaced no longer exists."})
+		return
+	}
+
+	tx, err := h.db.Tx()
+	if err != nil {
+		slog.Error("cannot start transaction to revert scheduled config", "tx_err", err, "scheduledConfigID", s.ID)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only put the old config back if nobody has edited it since, otherwise
+	// we'd throw their changes away
+	current, err := h.db.LockActiveConfigID(tx, ctx, s.Key)
+	if err != nil {
+		slog.Error("couldn't lock active config", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	if s.PublishedRevisionID == nil || current != *s.PublishedRevisionID {
+		tx.Rollback()
+		h.failScheduledConfig(ctx, s, models.SchedulePublished, []string{"The config was changed after this one was published, so it was left as it is."})
+		return
+	}
+
+	if err := h.db.MarkOldConfigsInactive(tx, ctx, s.Key); err != nil {
+		slog.Error("couldn't mark old configs inactive", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	id, err := h.db.SaveConfigRevision(tx, ctx, s.Key, previous.Config, s.CreatedBy)
+	if err != nil {
+		slog.Error("couldn't save reverted config", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	reverted, err := h.db.MarkScheduledConfigReverted(tx, ctx, s.ID, id)
+	if err != nil {
+		slog.Error("couldn't mark scheduled config reverted", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+	if !reverted {
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("couldn't commit scheduled config transaction", "tx_err", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+
+	h.db.AddAuditLog(ctx, guildID, s.CreatedBy, "REVERT_SCHEDULED_CONFIG", map[string]any{
+		"scheduled_config_id": s.ID,
+		"source_revision_id":  previous.ID,
+		"revision_id":         id,
+	})
+}
+
+// failScheduledConfig gives up on a scheduled config that couldn't be published
+// or reverted, leaving the reasons in the audit log
+func (h *Handler) failScheduledConfig(ctx context.Context, s *models.ScheduledConfig, status string, reasons []string) {
+	if err := h.db.MarkScheduledConfigFailed(ctx, s.ID, status); err != nil {
+		slog.Error("couldn't mark scheduled config failed", "sql_error", err.Error(), "scheduledConfigID", s.ID)
+		return
+	}
+
+	slog.Warn("scheduled config failed", "scheduledConfigID", s.ID, "key", s.Key, "reasons", reasons)
+	h.db.AddAuditLog(ctx, strings.TrimPrefix(s.Key, "guild-"), s.CreatedBy, "SCHEDULED_CONFIG_FAILED", map[string]any{
+		"scheduled_config_id": s.ID,
+		"stage":               status,
+		"errors":              reasons,
+	})
+}
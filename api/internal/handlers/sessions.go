@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/labstack/echo/v5"
+)
+
+// ListSessions lists the caller's active logins, marking the one making the request
+func (h *Handler) ListSessions(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	sessions, err := h.db.GetSessions(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve sessions", "sql_error", err.Error(), "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	current := c.Get("loginID").(string)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+// RevokeSession logs out one of the caller's sessions, which may be this one
+func (h *Handler) RevokeSession(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	revoked, err := h.db.RevokeSession(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		c.Logger().Error("couldn't revoke session", "sql_error", err.Error(), "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if !revoked {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok"})
+}
+
+// RevokeOtherSessions logs out every session of the caller's but this one
+func (h *Handler) RevokeOtherSessions(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	current := c.Get("loginID").(string)
+
+	revoked, err := h.db.RevokeOtherSessions(c.Request().Context(), userID, current)
+	if err != nil {
+		c.Logger().Error("couldn't revoke sessions", "sql_error", err.Error(), "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revoked": revoked})
+}
+
+// ipPrefix coarsens an IP address to its /24 (or /48 for IPv6), which is enough for
+// someone to recognise a session as theirs without us keeping their exact address
+func ipPrefix(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
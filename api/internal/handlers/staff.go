@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/permissions"
+)
+
+// staffAccessLogInterval is how often staff use of the same permission in the same
+// guild is written to the audit log. Every request would drown out everything else in there.
+const staffAccessLogInterval = time.Hour
+
+// noGuildID is what audit entries are filed under when they aren't about any one
+// guild, such as edits to config templates
+const noGuildID = "0"
+
+func (h *Handler) StaffStatus(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	return c.JSON(http.StatusOK, map[string]bool{"isStaff": h.isStaff(userID)})
+}
+
+// RevokeUserSessions logs a user out everywhere, for when someone shouldn't have
+// dashboard access any more and can't wait for their key to expire
+func (h *Handler) RevokeUserSessions(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	targetID := c.Param("userId")
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if !isSnowflake(targetID) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user id")
+	}
+
+	revoked, err := h.db.RevokeOtherSessions(c.Request().Context(), targetID, "")
+	if err != nil {
+		c.Logger().Error("couldn't revoke sessions", "sql_error", err.Error(), "targetID", targetID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), noGuildID, userID, "REVOKE_USER_SESSIONS", map[string]any{
+		"target_id": targetID,
+		"revoked":   revoked,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revoked": revoked})
+}
+
+// isStaff reports whether the user is one of the bot's operators, listed in STAFF
+func (h *Handler) isStaff(userID string) bool {
+	return slices.Contains(h.staff, userID)
+}
+
+// recordStaffAccess notes in the guild's audit log that a staff member got in on
+// their staff status alone, at most once per staffAccessLogInterval for each
+// permission, so reading a guild's config doesn't hide an edit made straight after
+func (h *Handler) recordStaffAccess(c *echo.Context, userID, guildID string, perm permissions.APIPermission) {
+	if !h.limits.allow("staff-"+userID+"-"+guildID+"-"+string(perm), staffAccessLogInterval) {
+		return
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), guildID, userID, "STAFF_ACCESS", map[string]any{
+		"permission": perm,
+		"method":     c.Request().Method,
+		"path":       c.Request().URL.Path,
+	})
+}
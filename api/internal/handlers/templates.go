@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/owdiscord/athena/api/internal/configs"
+	"github.com/owdiscord/athena/api/internal/permissions"
+	"gopkg.in/yaml.v3"
+)
+
+// templateKeyPrefix is what a template's name is stored under in the configs table
+const templateKeyPrefix = "template-"
+
+type templateSummary struct {
+	Name         string    `json:"name"`
+	Revision     int64     `json:"revision"`
+	EditedBy     string    `json:"edited_by"`
+	EditedByName *string   `json:"edited_by_name"`
+	EditedAt     time.Time `json:"edited_at"`
+}
+
+func (h *Handler) ListTemplates(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	revisions, err := h.db.GetActiveConfigsByPrefix(c.Request().Context(), templateKeyPrefix)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config templates", "sql_error", err.Error(), "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	templates := make([]templateSummary, 0, len(revisions))
+	for _, revision := range revisions {
+		templates = append(templates, templateSummary{
+			Name:         strings.TrimPrefix(revision.Key, templateKeyPrefix),
+			Revision:     revision.ID,
+			EditedBy:     revision.EditedBy,
+			EditedByName: revision.EditedByName,
+			EditedAt:     revision.EditedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"templates": templates})
+}
+
+func (h *Handler) GetTemplate(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	name := c.Param("name")
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if !configs.IsTemplateName(name) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid template name")
+	}
+
+	template, err := h.db.GetActiveConfig(c.Request().Context(), templateKeyPrefix+name)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config template", "sql_error", err.Error(), "template", name, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if template == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	usedBy, err := h.guildsExtending(c.Request().Context(), name)
+	if err != nil {
+		c.Logger().Error("couldn't find guilds using config template", "sql_error", err.Error(), "template", name, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	c.Response().Header().Set("ETag", revisionETag(template.ID))
+	return c.JSON(http.StatusOK, map[string]any{"name": name, "config": template.Config, "revision": template.ID, "usedBy": usedBy})
+}
+
+// SaveTemplate creates a template or saves a new revision of it. Guilds extending
+// it pick up the change the next time the bot reloads configs.
+func (h *Handler) SaveTemplate(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	name := c.Param("name")
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if !configs.IsTemplateName(name) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid template name")
+	}
+
+	var body struct {
+		Config       *string `json:"config"`
+		BaseRevision *int64  `json:"baseRevision"`
+	}
+	if err := c.Bind(&body); err != nil || body.Config == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "no config supplied")
+	}
+
+	base, err := baseRevision(c, body.BaseRevision)
+	if err != nil {
+		return err
+	}
+
+	config := strings.TrimSpace(*body.Config) + "\n"
+	key := templateKeyPrefix + name
+
+	current, err := h.db.GetActiveConfig(c.Request().Context(), key)
+	if err == nil && current != nil && config == current.Config {
+		c.Response().Header().Set("ETag", revisionETag(current.ID))
+		return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": current.ID})
+	}
+
+	if status, errs := validateGuildConfig(config); errs != nil {
+		return configErrors(c, status, errs)
+	}
+	// Templates are only one level deep, which keeps the bot from chasing cycles
+	if root, _ := configs.Parse(config); configs.Extends(root) != "" {
+		return configErrors(c, http.StatusUnprocessableEntity, []configs.Error{{Path: "extends", Message: "templates can't extend other templates"}})
+	}
+
+	id, err := h.publishConfig(c, key, config, userID, base, nil)
+	if conflict, ok := err.(*configConflictError); ok {
+		return h.configConflict(c, key, *base, conflict.current)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), noGuildID, userID, "SAVE_CONFIG_TEMPLATE", map[string]any{
+		"template":    name,
+		"revision_id": id,
+	})
+
+	c.Response().Header().Set("ETag", revisionETag(id))
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok", "revision": id})
+}
+
+// DeleteTemplate retires a template, keeping its revisions. A template that guilds
+// still extend can't be deleted, and the guilds in the way are listed instead.
+func (h *Handler) DeleteTemplate(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	name := c.Param("name")
+
+	if !h.isStaff(userID) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if !configs.IsTemplateName(name) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid template name")
+	}
+
+	usedBy, err := h.guildsExtending(c.Request().Context(), name)
+	if err != nil {
+		c.Logger().Error("couldn't find guilds using config template", "sql_error", err.Error(), "template", name, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if len(usedBy) > 0 {
+		return c.JSON(http.StatusConflict, map[string]any{
+			"errors": []string{"This template is still used by other servers. Remove extends from their configs first."},
+			"usedBy": usedBy,
+		})
+	}
+
+	deleted, err := h.db.DeactivateConfig(c.Request().Context(), templateKeyPrefix+name)
+	if err != nil {
+		c.Logger().Error("couldn't delete config template", "sql_error", err.Error(), "template", name, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+	if !deleted {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+
+	h.db.AddAuditLog(c.Request().Context(), noGuildID, userID, "DELETE_CONFIG_TEMPLATE", map[string]any{
+		"template": name,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{"result": "ok"})
+}
+
+// PreviewConfig returns the guild's config as the bot sees it, with the template it
+// extends merged in
+func (h *Handler) PreviewConfig(c *echo.Context) error {
+	userID := c.Get("userID").(string)
+	guildID := c.Param("guildId")
+
+	if !h.hasPermission(c, userID, guildID, permissions.ReadConfig) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	guildConfig, err := h.db.GetActiveConfig(c.Request().Context(), "guild-"+guildID)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve guild", "sql_error", err.Error(), "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	source := ""
+	var revision *int64
+	if guildConfig != nil {
+		source, revision = guildConfig.Config, &guildConfig.ID
+	}
+
+	root, err := configs.Parse(source)
+	if err != nil {
+		return configErrors(c, http.StatusUnprocessableEntity, []configs.Error{err.(configs.Error)})
+	}
+
+	name := configs.Extends(root)
+	if name == "" {
+		return c.JSON(http.StatusOK, map[string]any{"config": source, "revision": revision, "template": nil, "templateRevision": nil})
+	}
+
+	template, err := h.db.GetActiveConfig(c.Request().Context(), templateKeyPrefix+name)
+	if err != nil {
+		c.Logger().Error("couldn't retrieve config template", "sql_error", err.Error(), "template", name, "guildID", guildID, "userID", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+	}
+
+	// A missing template is treated as empty, as the bot does
+	var templateRoot *yaml.Node
+	var templateRevision *int64
+	if template != nil {
+		templateRevision = &template.ID
+		if templateRoot, err = configs.Parse(template.Config); err != nil {
+			return configErrors(c, http.StatusUnprocessableEntity, []configs.Error{err.(configs.Error)})
+		}
+	}
+
+	merged := ""
+	if node := configs.MergeTemplate(templateRoot, root); node != nil {
+		if merged, err = configs.Encode(node, configs.Indentation(root)); err != nil {
+			c.Logger().Error("couldn't encode merged config", "yaml_error", err.Error(), "guildID", guildID, "userID", userID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "server error")
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{"config": merged, "revision": revision, "template": name, "templateRevision": templateRevision})
+}
+
+// guildsExtending lists the IDs of guilds whose active config extends the template
+func (h *Handler) guildsExtending(ctx context.Context, name string) ([]string, error) {
+	candidates, err := h.db.GetActiveGuildConfigsContaining(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	guildIDs := []string{}
+	for _, candidate := range candidates {
+		root, err := configs.Parse(candidate.Config)
+		if err == nil && configs.Extends(root) == name {
+			guildIDs = append(guildIDs, strings.TrimPrefix(candidate.Key, "guild-"))
+		}
+	}
+	return guildIDs, nil
+}
@@ -1,28 +1,49 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/labstack/echo/v5"
 	"github.com/owdiscord/athena/api/internal/db"
 )
 
+// APIKeyAuth requires a valid API key in the X-Api-Key header
 func APIKeyAuth(db *db.DB) echo.MiddlewareFunc {
+	return apiKeyAuth(db, false)
+}
+
+// FormAPIKeyAuth is APIKeyAuth for downloads, such as case exports, which the
+// dashboard submits as a form and so can't set headers. The key may also come in the
+// POST body, but never the query string, where it'd end up in logs and history.
+func FormAPIKeyAuth(db *db.DB) echo.MiddlewareFunc {
+	return apiKeyAuth(db, true)
+}
+
+// apiKeyAuth sets userID to the key's owner and loginID to the login it belongs to,
+// so handlers never have to go back to wherever the key came from
+func apiKeyAuth(conn *db.DB, allowForm bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c *echo.Context) error {
 			apiKey := c.Request().Header.Get("X-Api-Key")
+			if apiKey == "" && allowForm {
+				apiKey = c.Request().PostFormValue("X-Api-Key")
+			}
 			if apiKey == "" {
 				return echo.NewHTTPError(http.StatusUnauthorized, "API key missing")
 			}
 
-			userID, err := db.GetUserIDByAPIKey(c.Request().Context(), apiKey)
+			userID, err := conn.GetUserIDByAPIKey(c.Request().Context(), apiKey)
 			if err != nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid API key")
 			}
 
-			go db.RefreshAPIKeyExpiry(c.Request().Context(), apiKey)
+			// The request is likely done before the refresh is, so it can't share
+			// the request's context, which is cancelled as soon as the handler returns
+			go conn.RefreshAPIKeyExpiry(context.WithoutCancel(c.Request().Context()), apiKey)
 
 			c.Set("userID", userID)
+			c.Set("loginID", db.LoginID(apiKey))
 			return next(c)
 		}
 	}
@@ -17,6 +17,18 @@ type APILogin struct {
 	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at"`
 }
 
+// APISession is an unexpired login as the user sees it, without the token. The
+// metadata is missing for logins made before it was recorded.
+type APISession struct {
+	ID         string     `db:"id" json:"id"`
+	LoggedInAt *time.Time `db:"logged_in_at" json:"logged_in_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at"`
+	UserAgent  *string    `db:"user_agent" json:"user_agent"`
+	IPPrefix   *string    `db:"ip_prefix" json:"ip_prefix"`
+	Current    bool       `db:"-" json:"current"`
+}
+
 type Guild struct {
 	ID        string     `db:"id" json:"id"`
 	Name      string     `db:"name" json:"name"`
@@ -34,12 +46,83 @@ type PermissionAssignment struct {
 }
 
 type Config struct {
+	ID       int64     `db:"id" json:"id"`
 	Key      string    `db:"key" json:"key"`
 	Config   string    `db:"config" json:"config"`
 	EditedBy string    `db:"edited_by" json:"edited_by"`
 	EditedAt time.Time `db:"edited_at" json:"edited_at"`
 }
 
+// ConfigRevision is a row from the configs table, active or not, with the editor's
+// username from api_user_info when we have it. Config is only filled in when a
+// single revision is requested.
+type ConfigRevision struct {
+	ID           int64     `db:"id" json:"id"`
+	Key          string    `db:"key" json:"key"`
+	Config       string    `db:"config" json:"config,omitempty"`
+	EditedBy     string    `db:"edited_by" json:"edited_by"`
+	EditedByName *string   `db:"edited_by_name" json:"edited_by_name"`
+	EditedAt     time.Time `db:"edited_at" json:"edited_at"`
+	IsActive     bool      `db:"is_active" json:"is_active"`
+}
+
+const (
+	DraftPending  = "pending"
+	DraftApproved = "approved"
+	DraftRejected = "rejected"
+)
+
+// ConfigDraft is a proposed config that only goes live once someone else approves it
+type ConfigDraft struct {
+	ID                  int64      `db:"id" json:"id"`
+	Key                 string     `db:"key" json:"key"`
+	Config              string     `db:"config" json:"config,omitempty"`
+	BaseRevisionID      *int64     `db:"base_revision_id" json:"base_revision_id"`
+	Status              string     `db:"status" json:"status"`
+	CreatedBy           string     `db:"created_by" json:"created_by"`
+	CreatedByName       *string    `db:"created_by_name" json:"created_by_name"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt           time.Time  `db:"updated_at" json:"updated_at"`
+	Version             int        `db:"version" json:"version"`
+	ReviewedBy          *string    `db:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt          *time.Time `db:"reviewed_at" json:"reviewed_at"`
+	ReviewComment       *string    `db:"review_comment" json:"review_comment"`
+	PublishedRevisionID *int64     `db:"published_revision_id" json:"published_revision_id"`
+}
+
+const (
+	ScheduleScheduled = "scheduled"
+	SchedulePublished = "published"
+	ScheduleReverted  = "reverted"
+	ScheduleCancelled = "cancelled"
+	ScheduleFailed    = "failed"
+)
+
+// ScheduledConfig is a config body waiting to go live at PublishAt, and optionally
+// to be swapped back for whatever it replaced at RevertAt
+type ScheduledConfig struct {
+	ID                  int64      `db:"id" json:"id"`
+	Key                 string     `db:"key" json:"key"`
+	Config              string     `db:"config" json:"config,omitempty"`
+	PublishAt           time.Time  `db:"publish_at" json:"publish_at"`
+	RevertAt            *time.Time `db:"revert_at" json:"revert_at"`
+	Status              string     `db:"status" json:"status"`
+	CreatedBy           string     `db:"created_by" json:"created_by"`
+	CreatedByName       *string    `db:"created_by_name" json:"created_by_name"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+	PreviousRevisionID  *int64     `db:"previous_revision_id" json:"previous_revision_id"`
+	PublishedRevisionID *int64     `db:"published_revision_id" json:"published_revision_id"`
+	RevertedRevisionID  *int64     `db:"reverted_revision_id" json:"reverted_revision_id"`
+}
+
+// GuildSnapshot is the bot's last look at which roles and channels exist in a guild
+type GuildSnapshot struct {
+	GuildID    string
+	RoleIDs    []string
+	ChannelIDs []string
+	UpdatedAt  time.Time
+}
+
 type AuditLog struct {
 	ID        int64     `db:"id" json:"id"`
 	GuildID   string    `db:"guild_id" json:"guild_id"`
@@ -55,3 +138,56 @@ type Archive struct {
 	CreatedAt time.Time  `db:"created_at" json:"created_at"`
 	ExpiresAt *time.Time `db:"expires_at" json:"expires_at"`
 }
+
+// CaseType mirrors the CaseTypes enum in backend/src/data/CaseTypes.ts
+type CaseType int
+
+const (
+	CaseTypeBan CaseType = iota + 1
+	CaseTypeUnban
+	CaseTypeNote
+	CaseTypeWarn
+	CaseTypeKick
+	CaseTypeMute
+	CaseTypeUnmute
+	CaseTypeDeleted
+	CaseTypeSoftban
+)
+
+var CaseTypeNames = map[string]CaseType{
+	"ban":     CaseTypeBan,
+	"unban":   CaseTypeUnban,
+	"note":    CaseTypeNote,
+	"warn":    CaseTypeWarn,
+	"kick":    CaseTypeKick,
+	"mute":    CaseTypeMute,
+	"unmute":  CaseTypeUnmute,
+	"deleted": CaseTypeDeleted,
+	"softban": CaseTypeSoftban,
+}
+
+type Case struct {
+	ID           int64      `db:"id" json:"id"`
+	GuildID      string     `db:"guild_id" json:"guild_id"`
+	CaseNumber   int        `db:"case_number" json:"case_number"`
+	UserID       string     `db:"user_id" json:"user_id"`
+	UserName     string     `db:"user_name" json:"user_name"`
+	ModID        *string    `db:"mod_id" json:"mod_id"`
+	ModName      *string    `db:"mod_name" json:"mod_name"`
+	Type         CaseType   `db:"type" json:"type"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	IsHidden     bool       `db:"is_hidden" json:"is_hidden"`
+	PPID         *string    `db:"pp_id" json:"pp_id"`
+	PPName       *string    `db:"pp_name" json:"pp_name"`
+	LogMessageID *string    `db:"log_message_id" json:"log_message_id"`
+	Notes        []CaseNote `db:"-" json:"notes,omitempty"`
+}
+
+type CaseNote struct {
+	ID        int64     `db:"id" json:"id"`
+	CaseID    int64     `db:"case_id" json:"case_id"`
+	ModID     *string   `db:"mod_id" json:"mod_id"`
+	ModName   *string   `db:"mod_name" json:"mod_name"`
+	Body      string    `db:"body" json:"body"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
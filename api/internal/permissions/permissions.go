@@ -1,13 +1,100 @@
+// Package permissions describes the API permissions a user can hold for a guild,
+// and how they relate to each other. It mirrors shared/src/apiPermissions.ts, so
+// any change to the hierarchy here needs to be made there as well.
 package permissions
 
+import "slices"
+
 type APIPermission string
 
 const (
 	ViewGuild    APIPermission = "VIEW_GUILD"
 	ReadConfig   APIPermission = "READ_CONFIG"
+	ViewCases    APIPermission = "VIEW_CASES"
 	EditConfig   APIPermission = "EDIT_CONFIG"
 	ManageAccess APIPermission = "MANAGE_ACCESS"
 	Owner        APIPermission = "OWNER"
 )
 
-var All = []APIPermission{ViewGuild, ReadConfig, EditConfig, ManageAccess, Owner}
+var All = []APIPermission{ViewGuild, ViewCases, ReadConfig, EditConfig, ManageAccess, Owner}
+
+// Node is a single permission in the hierarchy, along with the permissions
+// that are implicitly granted by holding it.
+type Node struct {
+	Permission APIPermission
+	Children   []Node
+}
+
+// Hierarchy is the permission tree. Holding a permission grants every
+// permission nested beneath it.
+var Hierarchy = []Node{
+	{Owner, []Node{
+		{ManageAccess, []Node{
+			{EditConfig, []Node{
+				{ReadConfig, []Node{
+					{ViewGuild, nil},
+				}},
+			}},
+			{ViewCases, []Node{
+				{ViewGuild, nil},
+			}},
+		}},
+	}},
+}
+
+// IsValid reports whether the permission is one we know about.
+func IsValid(perm APIPermission) bool {
+	return slices.Contains(All, perm)
+}
+
+// Implies reports whether holding granted also grants perm, either because
+// they're the same permission or because perm is nested beneath granted.
+func Implies(granted, perm APIPermission) bool {
+	if granted == perm {
+		return IsValid(perm)
+	}
+	node := find(Hierarchy, granted)
+	return node != nil && contains(node.Children, perm)
+}
+
+// Expand returns every permission granted by the given set, including the ones
+// implied through the hierarchy. Unknown permissions are dropped, and the result
+// is ordered the same way as All.
+func Expand(granted []APIPermission) []APIPermission {
+	expanded := make([]APIPermission, 0, len(All))
+	for _, perm := range All {
+		if Has(granted, perm) {
+			expanded = append(expanded, perm)
+		}
+	}
+	return expanded
+}
+
+// Has reports whether the granted set includes perm, directly or through the
+// hierarchy.
+func Has(granted []APIPermission, perm APIPermission) bool {
+	for _, g := range granted {
+		if Implies(g, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the node for perm anywhere in the tree
+func find(tree []Node, perm APIPermission) *Node {
+	for i := range tree {
+		if tree[i].Permission == perm {
+			return &tree[i]
+		}
+		if node := find(tree[i].Children, perm); node != nil {
+			return node
+		}
+	}
+	return nil
+}
+
+// contains reports whether perm appears anywhere in the tree
+func contains(tree []Node, perm APIPermission) bool {
+	return find(tree, perm) != nil
+}
@@ -0,0 +1,101 @@
+package permissions
+
+import (
+	"slices"
+	"testing"
+)
+
+// implied is every permission each one grants, itself included. It's written out
+// by hand rather than derived from Hierarchy, so a change to the tree has to be
+// made here as well.
+var implied = map[APIPermission][]APIPermission{
+	Owner:        {ViewGuild, ViewCases, ReadConfig, EditConfig, ManageAccess, Owner},
+	ManageAccess: {ViewGuild, ViewCases, ReadConfig, EditConfig, ManageAccess},
+	EditConfig:   {ViewGuild, ReadConfig, EditConfig},
+	ReadConfig:   {ViewGuild, ReadConfig},
+	ViewCases:    {ViewGuild, ViewCases},
+	ViewGuild:    {ViewGuild},
+}
+
+func TestImplies(t *testing.T) {
+	for _, granted := range All {
+		for _, perm := range All {
+			want := slices.Contains(implied[granted], perm)
+			if got := Implies(granted, perm); got != want {
+				t.Errorf("Implies(%s, %s) = %v, want %v", granted, perm, got, want)
+			}
+		}
+	}
+}
+
+func TestImpliesUnknown(t *testing.T) {
+	tests := []struct {
+		granted, perm APIPermission
+	}{
+		{"UNKNOWN", "UNKNOWN"},
+		{"UNKNOWN", ViewGuild},
+		{Owner, "UNKNOWN"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if Implies(tt.granted, tt.perm) {
+			t.Errorf("Implies(%q, %q) = true, want false", tt.granted, tt.perm)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []APIPermission
+		want    []APIPermission
+	}{
+		{"nothing", nil, []APIPermission{}},
+		{"owner", []APIPermission{Owner}, []APIPermission{ViewGuild, ViewCases, ReadConfig, EditConfig, ManageAccess, Owner}},
+		{"manage access", []APIPermission{ManageAccess}, []APIPermission{ViewGuild, ViewCases, ReadConfig, EditConfig, ManageAccess}},
+		{"edit config", []APIPermission{EditConfig}, []APIPermission{ViewGuild, ReadConfig, EditConfig}},
+		{"read config", []APIPermission{ReadConfig}, []APIPermission{ViewGuild, ReadConfig}},
+		{"view cases", []APIPermission{ViewCases}, []APIPermission{ViewGuild, ViewCases}},
+		{"view guild", []APIPermission{ViewGuild}, []APIPermission{ViewGuild}},
+		{"siblings", []APIPermission{ViewCases, EditConfig}, []APIPermission{ViewGuild, ViewCases, ReadConfig, EditConfig}},
+		{"duplicates", []APIPermission{ReadConfig, ReadConfig, ViewGuild}, []APIPermission{ViewGuild, ReadConfig}},
+		{"unknown dropped", []APIPermission{"UNKNOWN", ViewCases}, []APIPermission{ViewGuild, ViewCases}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expand(tt.granted); !slices.Equal(got, tt.want) {
+				t.Errorf("Expand(%v) = %v, want %v", tt.granted, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHas mirrors shared/src/apiPermissions.test.ts
+func TestHas(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []APIPermission
+		perm    APIPermission
+		want    bool
+	}{
+		{"directly granted", []APIPermission{ManageAccess}, ManageAccess, true},
+		{"not granted above", []APIPermission{ManageAccess}, Owner, false},
+		{"implied edit config", []APIPermission{ManageAccess}, EditConfig, true},
+		{"implied read config", []APIPermission{ManageAccess}, ReadConfig, true},
+		{"implied view cases", []APIPermission{ManageAccess}, ViewCases, true},
+		{"edit config doesn't grant manage access", []APIPermission{EditConfig}, ManageAccess, false},
+		{"edit config doesn't grant view cases", []APIPermission{EditConfig}, ViewCases, false},
+		{"view cases doesn't grant read config", []APIPermission{ViewCases}, ReadConfig, false},
+		{"view cases grants view guild", []APIPermission{ViewCases}, ViewGuild, true},
+		{"any of several", []APIPermission{ViewCases, ReadConfig}, ReadConfig, true},
+		{"nothing granted", nil, ViewGuild, false},
+		{"unknown granted", []APIPermission{"UNKNOWN"}, ViewGuild, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Has(tt.granted, tt.perm); got != tt.want {
+				t.Errorf("Has(%v, %s) = %v, want %v", tt.granted, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
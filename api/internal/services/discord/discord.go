@@ -1,24 +1,45 @@
 package discord
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// DefaultBaseURL is where Discord's API lives, used when Config.BaseURL is empty
+const DefaultBaseURL = "https://discord.com/api"
+
 const (
-	authURL  = "https://discord.com/api/oauth2/authorize"
-	tokenURL = "https://discord.com/api/oauth2/token"
-	userURL  = "https://discord.com/api/users/@me"
+	// maxRetries is how many times a rate limited request is retried
+	maxRetries = 2
+	// maxRetryAfter is the longest we'll wait out a rate limit. Anything longer
+	// and the user is better off being told to try again.
+	maxRetryAfter = 10 * time.Second
 )
 
+// defaultClient is used when Config.HTTPClient is nil. Unlike http.DefaultClient
+// it has a timeout, so a slow Discord can't hold requests open forever.
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
 type Config struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURI  string
 	Scopes       []string
+	// BaseURL is the API root the OAuth and user endpoints hang off, for pointing
+	// at a fake Discord. Empty means DefaultBaseURL.
+	BaseURL string
+	// HTTPClient makes the requests to Discord. Nil means a client with a 10
+	// second timeout.
+	HTTPClient *http.Client
 }
 
 type TokenResponse struct {
@@ -36,8 +57,10 @@ type User struct {
 	Avatar   string `json:"avatar"`
 }
 
-// AuthURL returns the URL to redirect the user to for authorization
-func (c *Config) AuthURL(state string) string {
+// AuthURL returns the URL to redirect the user to for authorization. If a PKCE
+// code verifier is given, its S256 challenge is sent along, and the same verifier
+// must then be passed to Exchange.
+func (c *Config) AuthURL(state, verifier string) string {
 	params := url.Values{
 		"client_id":     {c.ClientID},
 		"redirect_uri":  {c.RedirectURI},
@@ -45,11 +68,16 @@ func (c *Config) AuthURL(state string) string {
 		"scope":         {strings.Join(c.Scopes, " ")},
 		"state":         {state},
 	}
-	return authURL + "?" + params.Encode()
+	if verifier != "" {
+		params.Set("code_challenge", PKCEChallenge(verifier))
+		params.Set("code_challenge_method", "S256")
+	}
+	return c.baseURL() + "/oauth2/authorize?" + params.Encode()
 }
 
-// Exchange swaps an authorization code for an access token
-func (c *Config) Exchange(code string) (*TokenResponse, error) {
+// Exchange swaps an authorization code for an access token. verifier is the PKCE
+// code verifier the authorization was started with, or "" if there wasn't one.
+func (c *Config) Exchange(ctx context.Context, code, verifier string) (*TokenResponse, error) {
 	params := url.Values{
 		"client_id":     {c.ClientID},
 		"client_secret": {c.ClientSecret},
@@ -57,8 +85,18 @@ func (c *Config) Exchange(code string) (*TokenResponse, error) {
 		"grant_type":    {"authorization_code"},
 		"code":          {code},
 	}
+	if verifier != "" {
+		params.Set("code_verifier", verifier)
+	}
 
-	resp, err := http.PostForm(tokenURL, params)
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL()+"/oauth2/token", strings.NewReader(params.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
 	}
@@ -77,14 +115,15 @@ func (c *Config) Exchange(code string) (*TokenResponse, error) {
 }
 
 // GetUser fetches the authenticated user's profile
-func GetUser(accessToken string) (*User, error) {
-	req, err := http.NewRequest("GET", userURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := http.DefaultClient.Do(req)
+func (c *Config) GetUser(ctx context.Context, accessToken string) (*User, error) {
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL()+"/users/@me", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch user: %w", err)
 	}
@@ -101,3 +140,71 @@ func GetUser(accessToken string) (*User, error) {
 
 	return &user, nil
 }
+
+// do sends the request newRequest builds, waiting out and retrying when Discord
+// rate limits us. The request is built afresh for each attempt since its body can
+// only be read once. A 429 that can't be retried is returned as-is.
+func (c *Config) do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = defaultClient
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == maxRetries {
+			return resp, nil
+		}
+
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"))
+		if !ok || wait > maxRetryAfter {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryAfter reads a Retry-After header, which Discord gives in seconds, possibly
+// with a fraction
+func retryAfter(header string) (time.Duration, bool) {
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+func (c *Config) baseURL() string {
+	if c.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
+}
+
+// NewPKCEVerifier generates a random PKCE code verifier (RFC 7636)
+func NewPKCEVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PKCEChallenge derives the S256 code challenge for a verifier
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,192 @@
+package discord
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestPKCEChallenge(t *testing.T) {
+	// The example from RFC 7636, appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	if got, want := PKCEChallenge(verifier), "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("PKCEChallenge(%q) = %q, want %q", verifier, got, want)
+	}
+
+	a, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RFC 7636 wants 43 to 128 characters
+	if len(a) < 43 || len(a) > 128 {
+		t.Errorf("verifier %q is %d characters long", a, len(a))
+	}
+	if a == b {
+		t.Error("NewPKCEVerifier returned the same verifier twice")
+	}
+}
+
+func TestAuthURL(t *testing.T) {
+	c := &Config{ClientID: "client", RedirectURI: "http://localhost/callback", Scopes: []string{"identify", "email"}, BaseURL: "http://discord.test/api/"}
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		method    string
+	}{
+		{"with pkce", "verifier", PKCEChallenge("verifier"), "S256"},
+		{"without pkce", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(c.AuthURL("the-state", tt.verifier))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.Scheme + "://" + u.Host + u.Path; got != "http://discord.test/api/oauth2/authorize" {
+				t.Errorf("authorize endpoint is %s", got)
+			}
+
+			params := u.Query()
+			want := map[string]string{
+				"client_id":             "client",
+				"redirect_uri":          "http://localhost/callback",
+				"response_type":         "code",
+				"scope":                 "identify email",
+				"state":                 "the-state",
+				"code_challenge":        tt.challenge,
+				"code_challenge_method": tt.method,
+			}
+			for key, value := range want {
+				if got := params.Get(key); got != value {
+					t.Errorf("%s = %q, want %q", key, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+	}{
+		{"with pkce", "verifier"},
+		{"without pkce", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/oauth2/token" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("couldn't parse token request: %v", err)
+				}
+				form = r.PostForm
+				w.Write([]byte(`{"access_token":"access","token_type":"Bearer","expires_in":3600,"refresh_token":"refresh","scope":"identify"}`))
+			}))
+			defer server.Close()
+
+			c := &Config{ClientID: "client", ClientSecret: "secret", RedirectURI: "http://localhost/callback", BaseURL: server.URL}
+			token, err := c.Exchange(context.Background(), "the-code", tt.verifier)
+			if err != nil {
+				t.Fatalf("Exchange returned %v", err)
+			}
+			if token.AccessToken != "access" || token.RefreshToken != "refresh" {
+				t.Errorf("got token %+v", token)
+			}
+
+			want := map[string]string{
+				"client_id":     "client",
+				"client_secret": "secret",
+				"redirect_uri":  "http://localhost/callback",
+				"grant_type":    "authorization_code",
+				"code":          "the-code",
+				"code_verifier": tt.verifier,
+			}
+			for key, value := range want {
+				if got := form.Get(key); got != value {
+					t.Errorf("%s = %q, want %q", key, got, value)
+				}
+			}
+			if _, sent := form["code_verifier"]; sent != (tt.verifier != "") {
+				t.Errorf("code_verifier sent = %v, want %v", sent, tt.verifier != "")
+			}
+		})
+	}
+}
+
+func TestExchangeRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	c := &Config{BaseURL: server.URL}
+	if _, err := c.Exchange(context.Background(), "the-code", "wrong-verifier"); err == nil {
+		t.Error("Exchange succeeded when Discord rejected the code")
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		limited    int
+		wantStatus int
+		wantCalls  int
+	}{
+		{"retried", "0.01", 1, http.StatusOK, 2},
+		{"gives up after maxRetries", "0.01", maxRetries + 1, http.StatusTooManyRequests, maxRetries + 1},
+		{"wait too long", "60", 1, http.StatusTooManyRequests, 1},
+		{"no retry after", "", 1, http.StatusTooManyRequests, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tt.limited {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte(`{"id":"1","username":"someone"}`))
+			}))
+			defer server.Close()
+
+			c := &Config{BaseURL: server.URL}
+			resp, err := c.do(context.Background(), func() (*http.Request, error) {
+				return http.NewRequest("GET", server.URL+"/users/@me", nil)
+			})
+			if err != nil {
+				t.Fatalf("do returned %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
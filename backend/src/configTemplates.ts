@@ -0,0 +1,60 @@
+import { Configs } from "./data/Configs.js";
+import { logger } from "./logger.js";
+import { loadYamlSafely } from "./utils/loadYamlSafely.js";
+
+/**
+ * Guild configs can build on a shared template by declaring `extends: <name>`. The
+ * template lives under the `template-<name>` config key and is used as the base,
+ * with the guild's own config laid over it: mappings are merged key by key, while
+ * lists and plain values from the guild config replace the template's.
+ *
+ * Keep in sync with MergeTemplate in api/internal/configs/templates.go
+ */
+export async function applyConfigTemplate(config: any, configs: Configs): Promise<any> {
+  const name = config.extends;
+  delete config.extends;
+  if (typeof name !== "string") {
+    return config;
+  }
+
+  const row = await configs.getActiveByKey(`template-${name}`);
+  if (!row) {
+    logger.warn(`Config template "${name}" not found`);
+    return config;
+  }
+
+  const template = loadYamlSafely(row.config);
+  delete template.extends;
+  return mergeConfigs(template, config);
+}
+
+export function mergeConfigs(base: any, overlay: any): any {
+  const result = { ...base };
+  for (const [key, value] of Object.entries(overlay)) {
+    result[key] = isMapping(value) && isMapping(result[key]) ? mergeConfigs(result[key], value) : value;
+  }
+  return result;
+}
+
+/**
+ * Returns the IDs of guilds whose active config extends the given template
+ */
+export async function getGuildsExtendingTemplate(configs: Configs, name: string): Promise<string[]> {
+  const guildIds: string[] = [];
+  for (const row of await configs.getActive()) {
+    if (!row.key.startsWith("guild-") || !row.config.includes("extends")) continue;
+
+    try {
+      if (loadYamlSafely(row.config).extends === name) {
+        guildIds.push(row.key.slice("guild-".length));
+      }
+    } catch {
+      // A config that doesn't load can't extend anything
+    }
+  }
+  return guildIds;
+}
+
+function isMapping(value: unknown): value is Record<string, unknown> {
+  return value != null && typeof value === "object" && !Array.isArray(value);
+}
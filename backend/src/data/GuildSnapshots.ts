@@ -0,0 +1,32 @@
+import { Guild } from "discord.js";
+import moment from "moment-timezone";
+import { Repository } from "typeorm";
+import { DBDateFormat } from "../utils.js";
+import { BaseRepository } from "./BaseRepository.js";
+import { dataSource } from "./dataSource.js";
+import { GuildSnapshot } from "./entities/GuildSnapshot.js";
+
+/**
+ * Keeps a list of the role and channel IDs that exist in each guild, so the API
+ * can point out config references to deleted roles and channels
+ */
+export class GuildSnapshots extends BaseRepository {
+  private snapshots: Repository<GuildSnapshot>;
+
+  constructor() {
+    super();
+    this.snapshots = dataSource.getRepository(GuildSnapshot);
+  }
+
+  save(guild: Guild) {
+    return this.snapshots.upsert(
+      {
+        guild_id: guild.id,
+        role_ids: Array.from(guild.roles.cache.keys()),
+        channel_ids: Array.from(guild.channels.cache.keys()),
+        updated_at: moment.utc().format(DBDateFormat),
+      },
+      ["guild_id"],
+    );
+  }
+}
@@ -19,6 +19,15 @@ export class ApiLogin {
   @Column()
   expires_at: string;
 
+  @Column({ type: String, nullable: true })
+  last_used_at: string | null;
+
+  @Column({ type: String, nullable: true })
+  user_agent: string | null;
+
+  @Column({ type: String, nullable: true })
+  ip_prefix: string | null;
+
   @ManyToOne(() => ApiUserInfo, (userInfo) => userInfo.logins)
   @JoinColumn({ name: "user_id" })
   userInfo: Relation<ApiUserInfo>;
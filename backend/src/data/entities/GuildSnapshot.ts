@@ -0,0 +1,17 @@
+import { Column, Entity, PrimaryColumn } from "typeorm";
+
+@Entity("guild_snapshots")
+export class GuildSnapshot {
+  @Column()
+  @PrimaryColumn()
+  guild_id: string;
+
+  @Column("simple-json")
+  role_ids: string[];
+
+  @Column("simple-json")
+  channel_ids: string[];
+
+  @Column()
+  updated_at: string;
+}
@@ -20,6 +20,7 @@ import { DiscordJSError } from "./DiscordJSError.js";
 import { RecoverablePluginError } from "./RecoverablePluginError.js";
 import { SimpleError } from "./SimpleError.js";
 import { AllowedGuilds } from "./data/AllowedGuilds.js";
+import { applyConfigTemplate } from "./configTemplates.js";
 import { Configs } from "./data/Configs.js";
 import { FishFishError, initFishFish } from "./data/FishFish.js";
 import { GuildLogs } from "./data/GuildLogs.js";
@@ -336,7 +337,10 @@ connect().then(async () => {
         const row = await guildConfigs.getActiveByKey(key);
         if (row) {
           try {
-            const loaded = loadYamlSafely(row.config);
+            let loaded = loadYamlSafely(row.config);
+            if (id !== "global") {
+              loaded = await applyConfigTemplate(loaded, guildConfigs);
+            }
 
             if (loaded.success_emoji || loaded.error_emoji) {
               const deprecatedKeys = [] as string[];
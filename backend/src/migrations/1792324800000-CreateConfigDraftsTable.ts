@@ -0,0 +1,93 @@
+import { MigrationInterface, QueryRunner, Table } from "typeorm";
+
+export class CreateConfigDraftsTable1792324800000 implements MigrationInterface {
+  public async up(queryRunner: QueryRunner): Promise<any> {
+    await queryRunner.createTable(
+      new Table({
+        name: "config_drafts",
+        columns: [
+          {
+            name: "id",
+            type: "int",
+            isPrimary: true,
+            isGenerated: true,
+            generationStrategy: "increment",
+          },
+          {
+            name: "key",
+            type: "varchar",
+            length: "48",
+          },
+          {
+            name: "config",
+            type: "mediumtext",
+          },
+          {
+            name: "base_revision_id",
+            type: "int",
+            isNullable: true,
+            default: null,
+          },
+          {
+            name: "status",
+            type: "varchar",
+            length: "16",
+            default: "'pending'",
+          },
+          {
+            name: "created_by",
+            type: "bigint",
+          },
+          {
+            name: "created_at",
+            type: "datetime",
+            default: "now()",
+          },
+          {
+            name: "updated_at",
+            type: "datetime",
+            default: "now()",
+          },
+          {
+            name: "version",
+            type: "int",
+            unsigned: true,
+            default: 1,
+          },
+          {
+            name: "reviewed_by",
+            type: "bigint",
+            isNullable: true,
+            default: null,
+          },
+          {
+            name: "reviewed_at",
+            type: "datetime",
+            isNullable: true,
+            default: null,
+          },
+          {
+            name: "review_comment",
+            type: "text",
+            isNullable: true,
+          },
+          {
+            name: "published_revision_id",
+            type: "int",
+            isNullable: true,
+            default: null,
+          },
+        ],
+        indices: [
+          {
+            columnNames: ["key", "status"],
+          },
+        ],
+      }),
+    );
+  }
+
+  public async down(queryRunner: QueryRunner): Promise<any> {
+    await queryRunner.dropTable("config_drafts", true);
+  }
+}
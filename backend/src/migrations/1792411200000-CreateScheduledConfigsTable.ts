@@ -0,0 +1,87 @@
+import { MigrationInterface, QueryRunner, Table } from "typeorm";
+
+export class CreateScheduledConfigsTable1792411200000 implements MigrationInterface {
+  public async up(queryRunner: QueryRunner): Promise<any> {
+    await queryRunner.createTable(
+      new Table({
+        name: "scheduled_configs",
+        columns: [
+          {
+            name: "id",
+            type: "int",
+            isPrimary: true,
+            isGenerated: true,
+            generationStrategy: "increment",
+          },
+          {
+            name: "key",
+            type: "varchar",
+            length: "48",
+          },
+          {
+            name: "config",
+            type: "mediumtext",
+          },
+          {
+            name: "publish_at",
+            type: "datetime",
+          },
+          {
+            name: "revert_at",
+            type: "datetime",
+            isNullable: true,
+            default: null,
+          },
+          {
+            name: "status",
+            type: "varchar",
+            length: "16",
+            default: "'scheduled'",
+          },
+          {
+            name: "created_by",
+            type: "bigint",
+          },
+          {
+            name: "created_at",
+            type: "datetime",
+            default: "now()",
+          },
+          {
+            name: "previous_revision_id",
+            type: "int",
+            isNullable: true,
+            default: null,
+          },
+          {
+            name: "published_revision_id",
+            type: "int",
+            isNullable: true,
+            default: null,
+          },
+          {
+            name: "reverted_revision_id",
+            type: "int",
+            isNullable: true,
+            default: null,
+          },
+        ],
+        indices: [
+          {
+            columnNames: ["key", "status"],
+          },
+          {
+            columnNames: ["status", "publish_at"],
+          },
+          {
+            columnNames: ["status", "revert_at"],
+          },
+        ],
+      }),
+    );
+  }
+
+  public async down(queryRunner: QueryRunner): Promise<any> {
+    await queryRunner.dropTable("scheduled_configs", true);
+  }
+}
@@ -0,0 +1,35 @@
+import { MigrationInterface, QueryRunner, Table } from "typeorm";
+
+export class CreateGuildSnapshotsTable1792497600000 implements MigrationInterface {
+  public async up(queryRunner: QueryRunner): Promise<any> {
+    await queryRunner.createTable(
+      new Table({
+        name: "guild_snapshots",
+        columns: [
+          {
+            name: "guild_id",
+            type: "bigint",
+            isPrimary: true,
+          },
+          {
+            name: "role_ids",
+            type: "mediumtext",
+          },
+          {
+            name: "channel_ids",
+            type: "mediumtext",
+          },
+          {
+            name: "updated_at",
+            type: "datetime",
+            default: "now()",
+          },
+        ],
+      }),
+    );
+  }
+
+  public async down(queryRunner: QueryRunner): Promise<any> {
+    await queryRunner.dropTable("guild_snapshots", true);
+  }
+}
@@ -0,0 +1,34 @@
+import { MigrationInterface, QueryRunner, TableColumn } from "typeorm";
+
+export class AddSessionColumnsToApiLogins1792584000000 implements MigrationInterface {
+  public async up(queryRunner: QueryRunner): Promise<void> {
+    await queryRunner.addColumns("api_logins", [
+      new TableColumn({
+        name: "last_used_at",
+        type: "datetime",
+        isNullable: true,
+        default: null,
+      }),
+      new TableColumn({
+        name: "user_agent",
+        type: "varchar",
+        length: "255",
+        isNullable: true,
+        default: null,
+      }),
+      new TableColumn({
+        name: "ip_prefix",
+        type: "varchar",
+        length: "64",
+        isNullable: true,
+        default: null,
+      }),
+    ]);
+  }
+
+  public async down(queryRunner: QueryRunner): Promise<void> {
+    await queryRunner.dropColumn("api_logins", "ip_prefix");
+    await queryRunner.dropColumn("api_logins", "user_agent");
+    await queryRunner.dropColumn("api_logins", "last_used_at");
+  }
+}
@@ -1,5 +1,6 @@
 import { Snowflake } from "discord.js";
 import { GlobalPluginData } from "vety";
+import { getGuildsExtendingTemplate } from "../../../configTemplates.js";
 import { SECONDS } from "../../../utils.js";
 import { GuildConfigReloaderPluginType } from "../types.js";
 
@@ -10,16 +11,27 @@ export async function reloadChangedGuilds(pluginData: GlobalPluginData<GuildConf
 
   const changedConfigs = await pluginData.state.guildConfigs.getActiveLargerThanId(pluginData.state.highestConfigId);
   for (const item of changedConfigs) {
+    if (item.id > pluginData.state.highestConfigId) {
+      pluginData.state.highestConfigId = item.id;
+    }
+
+    if (item.key.startsWith("template-")) {
+      // Guilds built on the template pick up the change on reload
+      const templateName = item.key.slice("template-".length);
+      for (const guildId of await getGuildsExtendingTemplate(pluginData.state.guildConfigs, templateName)) {
+        // tslint:disable-next-line:no-console
+        console.log(`Config template ${templateName} changed, reloading guild ${guildId}`);
+        await pluginData.getVetyInstance().reloadGuild(guildId as Snowflake);
+      }
+      continue;
+    }
+
     if (!item.key.startsWith("guild-")) continue;
 
     const guildId = item.key.slice("guild-".length) as Snowflake;
     // tslint:disable-next-line:no-console
     console.log(`Config changed, reloading guild ${guildId}`);
     await pluginData.getVetyInstance().reloadGuild(guildId);
-
-    if (item.id > pluginData.state.highestConfigId) {
-      pluginData.state.highestConfigId = item.id;
-    }
   }
 
   pluginData.state.nextCheckTimeout = setTimeout(() => reloadChangedGuilds(pluginData), CHECK_INTERVAL);
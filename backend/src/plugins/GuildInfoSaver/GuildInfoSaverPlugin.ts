@@ -2,6 +2,8 @@ import { Guild } from "discord.js";
 import { guildPlugin, guildPluginEventListener } from "vety";
 import { AllowedGuilds } from "../../data/AllowedGuilds.js";
 import { ApiPermissionAssignments } from "../../data/ApiPermissionAssignments.js";
+import { GuildSnapshots } from "../../data/GuildSnapshots.js";
+import { logger } from "../../logger.js";
 import { MINUTES } from "../../utils.js";
 import { GuildInfoSaverPluginType, zGuildInfoSaverConfig } from "./types.js";
 
@@ -37,6 +39,9 @@ async function updateGuildInfo(guild: Guild) {
   const allowedGuilds = new AllowedGuilds();
   const existingData = (await allowedGuilds.find(guild.id))!;
   allowedGuilds.updateInfo(guild.id, guild.name, guild.iconURL(), guild.ownerId);
+  new GuildSnapshots()
+    .save(guild)
+    .catch((err) => logger.warn(`Failed to save guild snapshot for ${guild.id}: ${err}`));
 
   if (existingData.owner_id !== guild.ownerId || existingData.created_at === existingData.updated_at) {
     const apiPermissions = new ApiPermissionAssignments();
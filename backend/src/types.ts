@@ -7,6 +7,8 @@ export const zZeppelinGuildConfig = z.strictObject({
   prefix: z.string().optional(),
   levels: z.record(zSnowflake, z.number()).optional(),
   plugins: z.record(z.string(), z.unknown()).optional(),
+  // Resolved against the config templates when the config is loaded, see configTemplates.ts
+  extends: z.string().optional(),
 });
 
 /**
@@ -3,6 +3,7 @@ export enum ApiPermissions {
   ManageAccess = "MANAGE_ACCESS",
   EditConfig = "EDIT_CONFIG",
   ReadConfig = "READ_CONFIG",
+  ViewCases = "VIEW_CASES",
   ViewGuild = "VIEW_GUILD",
 }
 
@@ -16,6 +17,7 @@ export const permissionNames = {
   [ApiPermissions.ManageAccess]: "Bot manager",
   [ApiPermissions.EditConfig]: "Bot operator",
   [ApiPermissions.ReadConfig]: "Read config",
+  [ApiPermissions.ViewCases]: "View cases",
   [ApiPermissions.ViewGuild]: "View server",
 };
 
@@ -30,6 +32,9 @@ export const permissionHierarchy: TPermissionHierarchy = [
           ApiPermissions.ViewGuild,
         ]],
       ]],
+      [ApiPermissions.ViewCases, [
+        ApiPermissions.ViewGuild,
+      ]],
     ]],
   ]],
 ];